@@ -5,6 +5,8 @@ import (
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/bhenderson/web"
 )
 
 func Run(f Handler) Handler {
@@ -50,6 +52,18 @@ func (f Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.Path("", f)
 }
 
+// WithPathPolicy returns a handler that applies p to the request path before
+// it is split into segments for H.Path. See web.PathPolicy. Redirects go to
+// the path the handler sees, so behind http.StripPrefix put a redirect
+// policy on the outer handler instead.
+func (f Handler) WithPathPolicy(p web.PathPolicy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r = p.Apply(w, r); r != nil {
+			f.ServeHTTP(w, r)
+		}
+	})
+}
+
 type Middleware func(Handler) Handler
 
 type H struct {
@@ -149,6 +163,44 @@ func (h H) Path(path string, f Handler) {
 	h.checkPath(path, f)
 }
 
+// Version runs the handler of the version the request asks for, see
+// web.Versions. A version in the path, eg v2, is consumed like Path. Handlers
+// of type Handler run with h's middleware, anything else is served like
+// HandleHTTP, without adding to the response it writes. Unknown versions
+// Return 404 or 406.
+//
+//	h.Path("api", func(h H) {
+//		h.Version(&web.Versions{
+//			Versions: []web.Version{
+//				{Name: "1", Handler: Handler(v1), Deprecated: since},
+//				{Name: "2", Handler: Handler(v2)},
+//			},
+//		})
+//	})
+func (h H) Version(vs *web.Versions) {
+	v, inPath, status := vs.Select(h.Request, h.SubPath)
+	if v == nil {
+		h.Return(status)
+	}
+
+	v.SetHeaders(h.Header())
+	h.Request = web.WithVersion(h.Request, v.Name)
+	if inPath {
+		h.Next()
+	}
+
+	if f, ok := v.Handler.(Handler); ok {
+		h.Handle(f)
+		return
+	}
+	h.Handle(func(h H) {
+		h.Request.URL.Path = h.SubPath
+		v.Handler.ServeHTTP(h, h.Request)
+		// the handler wrote the response, don't add the status text to it.
+		h.Return("")
+	})
+}
+
 // TODO evaluate removing
 // PathEnd runs only when path matches the end of the request path
 // It uses the same matching rules as Path
@@ -6,7 +6,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/bhenderson/web"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -86,6 +88,122 @@ func TestHandle(t *testing.T) {
 	}
 }
 
+func TestWithPathPolicy(t *testing.T) {
+	f := Handler(func(h H) {
+		h.Path("a", func(h H) {
+			h.Path("b", func(h H) {
+				h.Return("b")
+			})
+		})
+	})
+
+	w := httptest.NewRecorder()
+	f.WithPathPolicy(web.PathClean).ServeHTTP(w, httptest.NewRequest("GET", "//a/./b", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "b", w.Body.String())
+
+	w = httptest.NewRecorder()
+	f.WithPathPolicy(web.PathRedirect|web.PathStripSlash).ServeHTTP(w, httptest.NewRequest("GET", "/a//b/", nil))
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/a/b", w.Header().Get("Location"))
+
+	// the policy of one handler doesn't change another.
+	w = httptest.NewRecorder()
+	f.ServeHTTP(w, httptest.NewRequest("GET", "//a/./b", nil))
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestVersion(t *testing.T) {
+	vs := &web.Versions{
+		Versions: []web.Version{
+			{Name: "1", Handler: Handler(func(h H) {
+				h.Path("users", func(h H) {
+					h.Get(func(h H) { h.Return("v1 users") })
+				})
+			}), Deprecated: time.Unix(1, 0)},
+			{Name: "2", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "v2 ", web.RequestVersion(r), " ", r.URL.Path)
+			})},
+		},
+		Header: "Api-Version",
+	}
+
+	f := Handler(func(h H) {
+		h.Path("api", func(h H) {
+			h.Version(vs)
+		})
+	})
+
+	assertRequest(t, "GET", "/api/v1/users", nil, 200, "v1 users", http.Header{"Deprecation": {"@1"}}, f)
+	assertRequest(t, "GET", "/api/users", nil, 200, "v2 2 users", http.Header{}, f)
+	assertRequest(t, "GET", "/api/v3/users", nil, 404, "Not Found", http.Header{}, f)
+}
+
+func TestCORS(t *testing.T) {
+	c := &web.CORS{AllowedOrigins: []string{"https://example.com"}}
+
+	f := Handler(func(h H) {
+		h.Path("api", func(h H) {
+			h.Use(CORS(c))
+			h.Path("users", func(h H) {
+				h.Get(func(h H) { h.Return("users") })
+				h.Post(nil)
+			})
+		})
+	})
+
+	serve := func(method string, header http.Header) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/api/users", nil)
+		r.Header = header
+		f.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("OPTIONS", http.Header{
+		"Origin":                        {"https://example.com"},
+		"Access-Control-Request-Method": {"POST"},
+	})
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, "", w.Body.String())
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+
+	w = serve("GET", http.Header{"Origin": {"https://example.com"}})
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "users", w.Body.String())
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestConditional(t *testing.T) {
+	f := Handler(func(h H) {
+		h.Use(Conditional)
+		h.Path("a", func(h H) {
+			h.Get(func(h H) { h.Return("hello") })
+		})
+	})
+
+	w := httptest.NewRecorder()
+	f.ServeHTTP(w, httptest.NewRequest("GET", "/a", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "hello", w.Body.String())
+	etag := w.Header().Get("ETag")
+	assert.NotEqual(t, "", etag)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/a", nil)
+	r.Header.Set("If-None-Match", etag)
+	f.ServeHTTP(w, r)
+	assert.Equal(t, 304, w.Code)
+	assert.Equal(t, "", w.Body.String())
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("GET", "/a", nil)
+	r.Header.Set("If-Match", `"other"`)
+	f.ServeHTTP(w, r)
+	assert.Equal(t, 412, w.Code)
+}
+
 func assertRequest(t *testing.T, verb, path string, body io.Reader, status int, result interface{}, headers http.Header, f Handler) {
 	defer func() {
 		e := recover()
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/bhenderson/web/conditional"
+)
+
+// Conditional is the Middleware equivalent of web.Conditional. When a GET or
+// HEAD request returns 200 OK, its preconditions are checked against the ETag
+// and Last-Modified headers, or a strong ETag computed from a []byte or string
+// body. Use it before any Middleware that encodes the body, so it sees the
+// encoded body:
+//
+//	h.Use(Conditional, HandleJSON)
+func Conditional(f Handler) Handler {
+	return func(h H) {
+		if h.Method != "GET" && h.Method != "HEAD" {
+			f(h)
+			return
+		}
+
+		defer h.Catch(func(h H) {
+			if h.Status != http.StatusOK {
+				return
+			}
+
+			v := conditional.FromHeader(h.Header())
+			if v.ETag == "" {
+				switch x := h.Response.Body.(type) {
+				case []byte:
+					v.ETag = conditional.ETag(x)
+				case string:
+					v.ETag = conditional.ETag([]byte(x))
+				}
+				if v.ETag != "" {
+					h.Header().Set("ETag", v.ETag)
+				}
+			}
+
+			switch conditional.Check(h.Request, v) {
+			case http.StatusNotModified:
+				h.Header().Del("Content-Type")
+				h.Status = http.StatusNotModified
+				h.Response.Body = ""
+			case http.StatusPreconditionFailed:
+				h.SetBody(http.StatusPreconditionFailed)
+			}
+		})
+
+		f(h)
+	}
+}
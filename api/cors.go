@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/bhenderson/web"
+)
+
+// CORS returns a Middleware that adds the CORS headers of c to responses, and
+// answers preflight requests with the verbs registered below it (see
+// web.CORS).
+//
+//	h.Path("api", func(h H) {
+//		h.Use(CORS(c))
+//		h.Path("users", func(h H) {
+//			h.Get(list)
+//			h.Post(create)
+//		})
+//	})
+func CORS(c *web.CORS) Middleware {
+	return func(f Handler) Handler {
+		return func(h H) {
+			if !web.IsPreflight(h.Request) {
+				c.SetHeaders(h.Header(), h.Request)
+				f(h)
+				return
+			}
+
+			// the verbs are known once handleAllowed halts.
+			defer func() {
+				r := recover()
+				if r == halt && h.hasAllowed() {
+					c.SetPreflightHeaders(h.Header(), h.Request, h.Header()[allowHeader])
+					h.Status = http.StatusNoContent
+					h.Response.Body = ""
+				}
+				if r != nil {
+					panic(r)
+				}
+			}()
+
+			f(h)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package conditional implements conditional requests (rfc7232): ETag and
+// Last-Modified validators, 304 Not Modified and 412 Precondition Failed.
+package conditional
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Validators describe the current representation of a resource. The zero
+// value means there is none.
+type Validators struct {
+	// ETag is a quoted entity-tag such as "abc" or W/"abc".
+	ETag         string
+	LastModified time.Time
+}
+
+func (v Validators) exists() bool {
+	return v.ETag != "" || !v.LastModified.IsZero()
+}
+
+// SetHeaders sets the ETag and Last-Modified headers of v on h.
+func (v Validators) SetHeaders(h http.Header) {
+	if v.ETag != "" {
+		h.Set("ETag", v.ETag)
+	}
+	if !v.LastModified.IsZero() {
+		h.Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// FromHeader returns the validators set on h.
+func FromHeader(h http.Header) Validators {
+	v := Validators{ETag: h.Get("ETag")}
+	if t, err := http.ParseTime(h.Get("Last-Modified")); err == nil {
+		v.LastModified = t
+	}
+	return v
+}
+
+// ETag returns a strong entity-tag for body.
+func ETag(body []byte) string {
+	sum := sha1.Sum(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`
+}
+
+// Check evaluates the preconditions of r against the current validators of
+// the resource, in the order of rfc7232 section 6. It returns 0 if the
+// request should be performed, http.StatusNotModified or
+// http.StatusPreconditionFailed.
+//
+// If-Match uses the strong comparison and If-None-Match the weak one. Dates
+// are only compared if v has a LastModified, and at a precision of seconds.
+func Check(r *http.Request, v Validators) int {
+	if im := r.Header.Get("If-Match"); im != "" {
+		if !matchETag(im, v, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && !v.LastModified.IsZero() {
+		if v.LastModified.Truncate(time.Second).After(ius) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	safe := r.Method == "GET" || r.Method == "HEAD"
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if matchETag(inm, v, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && safe && !v.LastModified.IsZero() {
+		if !v.LastModified.Truncate(time.Second).After(ims) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// matchETag reports whether the If-Match or If-None-Match header value list
+// matches v.
+func matchETag(list string, v Validators, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return v.exists()
+	}
+	if v.ETag == "" {
+		return false
+	}
+
+	for {
+		list = strings.TrimLeft(list, " \t,")
+		if list == "" {
+			return false
+		}
+		tag, rest, ok := scanETag(list)
+		if !ok {
+			return false
+		}
+		list = rest
+		if equalETags(tag, v.ETag, weak) {
+			return true
+		}
+	}
+}
+
+// scanETag returns the entity-tag at the start of s and the rest of s.
+func scanETag(s string) (tag, rest string, ok bool) {
+	start := 0
+	if strings.HasPrefix(s, "W/") {
+		start = 2
+	}
+	if len(s) < start+2 || s[start] != '"' {
+		return "", "", false
+	}
+	end := strings.IndexByte(s[start+1:], '"')
+	if end < 0 {
+		return "", "", false
+	}
+	end += start + 2
+	return s[:end], s[end:], true
+}
+
+func equalETags(a, b string, weak bool) bool {
+	if weak {
+		return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+	}
+	return a == b && !strings.HasPrefix(a, "W/")
+}
+
+// Write replies to r with status, which is a result of Check. For 304 Not
+// Modified the validators of v are set.
+func Write(w http.ResponseWriter, r *http.Request, status int, v Validators) {
+	if status == http.StatusNotModified {
+		h := w.Header()
+		// rfc7232 4.1
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		v.SetHeaders(h)
+		w.WriteHeader(status)
+		return
+	}
+	http.Error(w, "412 precondition failed", http.StatusPreconditionFailed)
+}
+
+type bufferWriter struct {
+	http.ResponseWriter
+
+	status int
+	buf    bytes.Buffer
+}
+
+func (bw *bufferWriter) WriteHeader(status int) {
+	if bw.status == 0 {
+		bw.status = status
+	}
+}
+
+func (bw *bufferWriter) Write(p []byte) (int, error) {
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+	return bw.buf.Write(p)
+}
+
+// ConditionalMiddleware implements web.Middleware. It buffers the 200 OK
+// responses to GET and HEAD requests and checks the preconditions of the
+// request against them: the ETag and Last-Modified headers set by the
+// handler, or a strong ETag computed from the body if the handler sets none.
+// It replies with 304 Not Modified or 412 Precondition Failed instead of the
+// response where needed. Other requests are passed through; to check the
+// preconditions of PUT, PATCH or DELETE use Check before changing anything,
+// as web.Method does with Validate.
+//
+// Buffering means the response can't be flushed early.
+func ConditionalMiddleware(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bw := &bufferWriter{ResponseWriter: w}
+		next.ServeHTTP(bw, r)
+
+		if bw.status == 0 {
+			bw.status = http.StatusOK
+		}
+
+		if bw.status == http.StatusOK {
+			v := FromHeader(w.Header())
+			if v.ETag == "" && (r.Method == "GET" || bw.buf.Len() > 0) {
+				v.ETag = ETag(bw.buf.Bytes())
+				w.Header().Set("ETag", v.ETag)
+			}
+			if status := Check(r, v); status != 0 {
+				Write(w, r, status, v)
+				return
+			}
+		}
+
+		w.WriteHeader(bw.status)
+		w.Write(bw.buf.Bytes())
+	}
+}
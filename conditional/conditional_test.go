@@ -0,0 +1,126 @@
+package conditional
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	mod := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	before := mod.Add(-time.Hour).Format(http.TimeFormat)
+	at := mod.Format(http.TimeFormat)
+
+	v := Validators{ETag: `"abc"`, LastModified: mod.Add(500 * time.Millisecond)}
+	weak := Validators{ETag: `W/"abc"`}
+
+	tests := []struct {
+		method, header, value string
+		v                     Validators
+		exp                   int
+	}{
+		{"GET", "", "", v, 0},
+		{"GET", "If-None-Match", `"abc"`, v, 304},
+		{"GET", "If-None-Match", `"x", W/"abc"`, v, 304},
+		{"GET", "If-None-Match", `"abc"`, weak, 304},
+		{"GET", "If-None-Match", `"x"`, v, 0},
+		{"GET", "If-None-Match", `*`, v, 304},
+		{"GET", "If-None-Match", `*`, Validators{}, 0},
+		{"PUT", "If-None-Match", `*`, v, 412},
+		{"PUT", "If-None-Match", `*`, Validators{}, 0},
+		{"GET", "If-Modified-Since", at, v, 304},
+		{"GET", "If-Modified-Since", before, v, 0},
+		{"PUT", "If-Modified-Since", at, v, 0},
+		{"GET", "If-Modified-Since", "garbage", v, 0},
+		{"PUT", "If-Match", `"abc"`, v, 0},
+		{"PUT", "If-Match", `"x", "abc"`, v, 0},
+		{"PUT", "If-Match", `"x"`, v, 412},
+		{"PUT", "If-Match", `W/"abc"`, weak, 412},
+		{"PUT", "If-Match", `*`, v, 0},
+		{"PUT", "If-Match", `*`, Validators{}, 412},
+		{"PUT", "If-Match", `bad`, v, 412},
+		{"DELETE", "If-Unmodified-Since", at, v, 0},
+		{"DELETE", "If-Unmodified-Since", before, v, 412},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, "/", nil)
+		if test.header != "" {
+			r.Header.Set(test.header, test.value)
+		}
+		assert.Equal(t, test.exp, Check(r, test.v), fmt.Sprintf("%s %s: %s", test.method, test.header, test.value))
+	}
+}
+
+func TestCheck_Order(t *testing.T) {
+	v := Validators{ETag: `"abc"`, LastModified: time.Now()}
+
+	// If-None-Match wins over If-Modified-Since
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("If-None-Match", `"x"`)
+	r.Header.Set("If-Modified-Since", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.Equal(t, 0, Check(r, v))
+
+	// If-Match wins over If-Unmodified-Since
+	r = httptest.NewRequest("PUT", "/", nil)
+	r.Header.Set("If-Match", `"abc"`)
+	r.Header.Set("If-Unmodified-Since", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+	assert.Equal(t, 0, Check(r, v))
+}
+
+func TestConditionalMiddleware(t *testing.T) {
+	body := "hello"
+	etag := ETag([]byte(body))
+
+	h := ConditionalMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tagged":
+			w.Header().Set("ETag", `"v1"`)
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, body)
+	}))
+
+	serve := func(method, path, inm string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if inm != "" {
+			r.Header.Set("If-None-Match", inm)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("GET", "/", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, body, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = serve("GET", "/", etag)
+	assert.Equal(t, 304, w.Code)
+	assert.Equal(t, "", w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, "", w.Header().Get("Content-Type"))
+
+	w = serve("GET", "/tagged", `"v1"`)
+	assert.Equal(t, 304, w.Code)
+
+	w = serve("GET", "/tagged", etag)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `"v1"`, w.Header().Get("ETag"))
+
+	w = serve("GET", "/missing", "*")
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, "", w.Header().Get("ETag"))
+
+	w = serve("POST", "/", etag)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "", w.Header().Get("ETag"))
+}
@@ -0,0 +1,182 @@
+package web
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures cross-origin resource sharing. Set it on Method or
+// Resource, or use api.CORS. The methods of a preflight response come from
+// the handlers that are defined.
+type CORS struct {
+	// AllowedOrigins are origins such as https://example.com. An origin may
+	// contain one *, as in https://*.example.com, and * alone allows any
+	// origin, unless AllowCredentials is set.
+	AllowedOrigins []string
+
+	// AllowedOriginPatterns are matched against origins not in
+	// AllowedOrigins.
+	AllowedOriginPatterns []*regexp.Regexp
+
+	// AllowOriginFunc, if set, is called for origins that nothing else
+	// allows.
+	AllowOriginFunc func(origin string) bool
+
+	// AllowCredentials lets the browser send cookies and expose the
+	// response. The origin is always echoed back instead of *. As that would
+	// let any site make requests with the cookies of the user, * in
+	// AllowedOrigins allows no origin then; list the origins, or use
+	// AllowedOriginPatterns or AllowOriginFunc.
+	AllowCredentials bool
+
+	// AllowedHeaders are the request headers a preflight may ask for. If
+	// nil, any requested headers are allowed.
+	AllowedHeaders []string
+
+	// ExposedHeaders are the response headers scripts may read.
+	ExposedHeaders []string
+
+	// MaxAge is how long a preflight response may be cached.
+	MaxAge time.Duration
+}
+
+// IsPreflight reports whether r is a CORS preflight request.
+func IsPreflight(r *http.Request) bool {
+	return r.Method == "OPTIONS" &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// AllowOrigin reports whether origin is allowed.
+func (c *CORS) AllowOrigin(origin string) bool {
+	_, ok := c.allowOrigin(origin)
+	return ok
+}
+
+// allowOrigin returns the value of Access-Control-Allow-Origin for origin.
+func (c *CORS) allowOrigin(origin string) (string, bool) {
+	if origin == "" {
+		return "", false
+	}
+
+	for _, o := range c.AllowedOrigins {
+		if o == "*" {
+			if c.AllowCredentials {
+				continue
+			}
+			return "*", true
+		}
+		if matchOrigin(o, origin) {
+			return origin, true
+		}
+	}
+	for _, re := range c.AllowedOriginPatterns {
+		if re.MatchString(origin) {
+			return origin, true
+		}
+	}
+	if c.AllowOriginFunc != nil && c.AllowOriginFunc(origin) {
+		return origin, true
+	}
+	return "", false
+}
+
+func matchOrigin(pattern, origin string) bool {
+	pattern, origin = strings.ToLower(pattern), strings.ToLower(origin)
+	i := strings.IndexByte(pattern, '*')
+	if i < 0 {
+		return pattern == origin
+	}
+	prefix, suffix := pattern[:i], pattern[i+1:]
+	return len(origin) > len(prefix)+len(suffix) &&
+		strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix)
+}
+
+// SetHeaders sets the CORS headers of an actual (not preflight) response to
+// r. It reports whether the origin of r is allowed; if not, nothing is set
+// other than Vary.
+func (c *CORS) SetHeaders(h http.Header, r *http.Request) bool {
+	h.Add("Vary", "Origin")
+
+	allow, ok := c.allowOrigin(r.Header.Get("Origin"))
+	if !ok {
+		return false
+	}
+
+	h.Set("Access-Control-Allow-Origin", allow)
+	if c.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if len(c.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(c.ExposedHeaders, ", "))
+	}
+	return true
+}
+
+// SetPreflightHeaders sets the headers of a response to the preflight request
+// r for a resource that allows methods. It reports whether the origin, method
+// and headers asked for are allowed; if not, no CORS headers are set and the
+// browser will fail the request.
+func (c *CORS) SetPreflightHeaders(h http.Header, r *http.Request, methods []string) bool {
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	allow, ok := c.allowOrigin(r.Header.Get("Origin"))
+	if !ok {
+		return false
+	}
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !containsToken(methods, method, false) {
+		return false
+	}
+
+	var headers []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				headers = append(headers, name)
+			}
+		}
+	}
+	if c.AllowedHeaders != nil {
+		for _, name := range headers {
+			if !containsToken(c.AllowedHeaders, name, true) {
+				return false
+			}
+		}
+	}
+
+	h.Set("Access-Control-Allow-Origin", allow)
+	h.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if c.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if c.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.MaxAge/time.Second)))
+	}
+	return true
+}
+
+// Preflight replies to the preflight request r with 204 No Content, see
+// SetPreflightHeaders.
+func (c *CORS) Preflight(w http.ResponseWriter, r *http.Request, methods []string) {
+	c.SetPreflightHeaders(w.Header(), r, methods)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func containsToken(list []string, s string, fold bool) bool {
+	for _, v := range list {
+		if v == s || fold && strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
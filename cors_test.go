@@ -0,0 +1,160 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS_AllowOrigin(t *testing.T) {
+	c := &CORS{
+		AllowedOrigins:        []string{"https://example.com", "https://*.example.net"},
+		AllowedOriginPatterns: []*regexp.Regexp{regexp.MustCompile(`^http://localhost:\d+$`)},
+		AllowOriginFunc: func(origin string) bool {
+			return origin == "https://partner.org"
+		},
+	}
+
+	tests := []struct {
+		origin string
+		exp    bool
+	}{
+		{"https://example.com", true},
+		{"HTTPS://EXAMPLE.COM", true},
+		{"http://example.com", false},
+		{"https://a.example.net", true},
+		{"https://example.net", false},
+		{"https://a.example.net.evil.com", false},
+		{"http://localhost:8080", true},
+		{"https://partner.org", true},
+		{"https://other.org", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.exp, c.AllowOrigin(test.origin), test.origin)
+	}
+}
+
+func TestMethod_CORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+
+	c := &CORS{
+		AllowedOrigins: []string{"https://example.com"},
+		AllowedHeaders: []string{"Content-Type", "X-Token"},
+		ExposedHeaders: []string{"X-Total"},
+		MaxAge:         10 * time.Minute,
+	}
+	m := &Method{Get: ok, Put: ok, CORS: c}
+
+	serve := func(method string, header map[string]string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/", nil)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+
+	// preflight
+	w := serve("OPTIONS", map[string]string{
+		"Origin":                         "https://example.com",
+		"Access-Control-Request-Method":  "PUT",
+		"Access-Control-Request-Headers": "content-type, x-token",
+	})
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, OPTIONS, PUT", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "content-type, x-token", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header()["Vary"])
+
+	// method not defined
+	w = serve("OPTIONS", map[string]string{
+		"Origin":                        "https://example.com",
+		"Access-Control-Request-Method": "DELETE",
+	})
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// header not allowed
+	w = serve("OPTIONS", map[string]string{
+		"Origin":                         "https://example.com",
+		"Access-Control-Request-Method":  "GET",
+		"Access-Control-Request-Headers": "X-Other",
+	})
+	assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// plain OPTIONS
+	w = serve("OPTIONS", nil)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "GET, OPTIONS, PUT", w.Header().Get("Allow"))
+
+	// actual request
+	w = serve("GET", map[string]string{"Origin": "https://example.com"})
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Total", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	w = serve("GET", map[string]string{"Origin": "https://evil.com"})
+	assert.Equal(t, "ok", w.Body.String())
+	assert.Equal(t, "", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_Any(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Origin", "https://example.com")
+
+	h := http.Header{}
+	(&CORS{AllowedOrigins: []string{"*"}}).SetHeaders(h, r)
+	assert.Equal(t, "*", h.Get("Access-Control-Allow-Origin"))
+
+	// any origin with credentials is refused
+	h = http.Header{}
+	c := &CORS{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	assert.False(t, c.SetHeaders(h, r))
+	assert.Equal(t, "", h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "", h.Get("Access-Control-Allow-Credentials"))
+
+	// unless something else allows it
+	h = http.Header{}
+	c.AllowOriginFunc = func(origin string) bool { return origin == "https://example.com" }
+	assert.True(t, c.SetHeaders(h, r))
+	assert.Equal(t, "https://example.com", h.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", h.Get("Access-Control-Allow-Credentials"))
+}
+
+func TestResource_CORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	rs := &Resource{
+		Index: ok,
+		Show:  ok,
+		MemberActions: map[string]*Method{
+			"cancel": {Post: ok},
+		},
+		CORS: &CORS{AllowedOrigins: []string{"*"}},
+	}
+
+	preflight := func(path, method string) http.Header {
+		r := httptest.NewRequest("OPTIONS", path, nil)
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Access-Control-Request-Method", method)
+		w := httptest.NewRecorder()
+		rs.ServeHTTP(w, r)
+		return w.Header()
+	}
+
+	assert.Equal(t, "GET, OPTIONS", preflight("/users/", "GET").Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "GET, OPTIONS", preflight("/users/1", "GET").Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "OPTIONS, POST", preflight("/users/1/cancel", "POST").Get("Access-Control-Allow-Methods"))
+}
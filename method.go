@@ -2,7 +2,10 @@ package web
 
 import (
 	"net/http"
+	"sort"
 	"strings"
+
+	"github.com/bhenderson/web/conditional"
 )
 
 // Method is an http.Handler that routes requests according to the HTTP verb.
@@ -20,20 +23,49 @@ type Method struct {
 	Post,
 	Put http.Handler
 
+	// Methods has handlers for any other method token, such as PROPFIND or
+	// PURGE. Tokens are case-sensitive. The fields above take precedence for
+	// their methods.
+	Methods map[string]http.Handler
+
 	// Any handler will respond to any method.
 	Any http.Handler
 
 	// MethodNotAllowed handler is called if the appropriate method handler is
 	// not defined. Defaults to list of defined methods.
 	MethodNotAllowed http.Handler
+
+	// CORS, if set, answers preflight requests with the defined methods and
+	// adds the CORS headers to every other response.
+	CORS *CORS
+
+	// Validate, if set, returns the current validators of the resource. The
+	// preconditions of the request (If-Match, If-None-Match and so on) are
+	// checked against them before the handler runs, so PUT, PATCH and DELETE
+	// can use If-Match for optimistic concurrency. GET and HEAD responses
+	// get the ETag and Last-Modified headers. Any and MethodNotAllowed
+	// are not checked.
+	Validate func(*http.Request) Validators
 }
 
+// Validators describe the current representation of a resource. See
+// conditional.Validators.
+type Validators = conditional.Validators
+
 // ServeHTTP implements the http.Handler interface for Method.
 // Explicit methods are tried first, then Any is used as a fallback.
 // MethodNotAllowed is used for any missing method with defaults.
 func (m *Method) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var f http.Handler
 
+	if m.CORS != nil {
+		if IsPreflight(r) {
+			m.CORS.Preflight(w, r, allowedMethods(m))
+			return
+		}
+		m.CORS.SetHeaders(w.Header(), r)
+	}
+
 	switch r.Method {
 	case "DELETE":
 		f = m.Delete
@@ -52,6 +84,9 @@ func (m *Method) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		f = m.Put
 	case "OPTIONS":
 		f = m.Options
+		if f == nil {
+			f = m.Methods["OPTIONS"]
+		}
 		if f == nil {
 			// The default OPTIONS response.
 			// rfc2616 9.2
@@ -62,6 +97,13 @@ func (m *Method) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if f == nil {
+		f = m.Methods[r.Method]
+	}
+
+	// preconditions are only checked for the handlers of a method.
+	validate := f != nil && m.Validate != nil
+
 	if f == nil {
 		f = m.Any
 	}
@@ -76,6 +118,17 @@ func (m *Method) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if validate {
+		v := m.Validate(r)
+		if status := conditional.Check(r, v); status != 0 {
+			conditional.Write(w, r, status, v)
+			return
+		}
+		if r.Method == "GET" || r.Method == "HEAD" {
+			v.SetHeaders(w.Header())
+		}
+	}
+
 	f.ServeHTTP(w, r)
 }
 
@@ -100,9 +153,8 @@ func setAllowHeader(w http.ResponseWriter, allowed ...string) {
 }
 
 func allowedMethods(m *Method) []string {
-	a := make([]string, 0, 6)
+	a := make([]string, 0, 7)
 
-	// not including OPTIONS for now, but open to discussion.
 	if m.Delete != nil {
 		a = append(a, "DELETE")
 	}
@@ -115,6 +167,8 @@ func allowedMethods(m *Method) []string {
 	if m.Head != nil {
 		a = append(a, "HEAD")
 	}
+	// OPTIONS always has a default response.
+	a = append(a, "OPTIONS")
 	if m.Patch != nil {
 		a = append(a, "PATCH")
 	}
@@ -124,5 +178,15 @@ func allowedMethods(m *Method) []string {
 	if m.Put != nil {
 		a = append(a, "PUT")
 	}
+
+	if len(m.Methods) == 0 {
+		return a
+	}
+	for method, h := range m.Methods {
+		if h != nil && !containsToken(a, method, false) {
+			a = append(a, method)
+		}
+	}
+	sort.Strings(a)
 	return a
 }
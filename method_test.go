@@ -54,7 +54,7 @@ func TestMethod_Get_MethodNotFound(t *testing.T) {
 	w = testRequest(t, "POST", m)
 	assert.Equal(t, 405, w.Code)
 	assert.Equal(t, "405 method not allowed\n", w.Body.String())
-	assert.Equal(t, "GET, PUT", w.HeaderMap.Get("Allow"))
+	assert.Equal(t, "GET, OPTIONS, PUT", w.HeaderMap.Get("Allow"))
 }
 
 func TestMethod_Options(t *testing.T) {
@@ -74,11 +74,94 @@ func TestMethod_Options(t *testing.T) {
 	w := testRequest(t, "OPTIONS", m)
 	assert.Equal(t, 200, w.Code)
 	assert.Equal(t, "", w.Body.String())
-	assert.Equal(t, "GET, PUT", w.HeaderMap.Get("Allow"))
+	assert.Equal(t, "GET, OPTIONS, PUT", w.HeaderMap.Get("Allow"))
 	// Server implementation apparently takes care of this.
 	// assert.Equal(t, "0", w.HeaderMap.Get("Content-Length"))
 }
 
+func TestMethod_Methods(t *testing.T) {
+	handler := func(n string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, n, http.StatusOK)
+		}
+	}
+
+	m := &Method{
+		Get: handler("get"),
+		Methods: map[string]http.Handler{
+			"PROPFIND": handler("propfind"),
+			"MKCOL":    handler("mkcol"),
+			"GET":      handler("ignored"),
+		},
+	}
+
+	_, body := testMethod(t, "PROPFIND", m)
+	assert.Equal(t, "propfind\n", body)
+
+	_, body = testMethod(t, "GET", m)
+	assert.Equal(t, "get\n", body)
+
+	w := testRequest(t, "PURGE", m)
+	assert.Equal(t, 405, w.Code)
+	assert.Equal(t, "GET, MKCOL, OPTIONS, PROPFIND", w.HeaderMap.Get("Allow"))
+
+	m.Methods["OPTIONS"] = handler("options")
+	_, body = testMethod(t, "OPTIONS", m)
+	assert.Equal(t, "options\n", body)
+}
+
+func TestMethod_Validate(t *testing.T) {
+	var h http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, r.Method, http.StatusOK)
+	}
+
+	version := `"2"`
+	m := &Method{
+		Get: h,
+		Put: h,
+		Validate: func(r *http.Request) Validators {
+			return Validators{ETag: version}
+		},
+	}
+
+	serve := func(method, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("GET", "If-None-Match", `"1"`)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+	w = serve("GET", "If-None-Match", `"2"`)
+	assert.Equal(t, 304, w.Code)
+
+	w = serve("PUT", "If-Match", `"1"`)
+	assert.Equal(t, 412, w.Code)
+	assert.Equal(t, "412 precondition failed\n", w.Body.String())
+
+	w = serve("PUT", "If-Match", `"2"`)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "PUT\n", w.Body.String())
+
+	// no handler, no precondition check
+	w = serve("DELETE", "If-Match", `"1"`)
+	assert.Equal(t, 405, w.Code)
+
+	// nor for the fallbacks
+	m.MethodNotAllowed = MethodNotAllowedHandler("GET", "PUT")
+	w = serve("DELETE", "If-Match", `"1"`)
+	assert.Equal(t, 405, w.Code)
+
+	m.Any = h
+	w = serve("DELETE", "If-Match", `"1"`)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "DELETE\n", w.Body.String())
+}
+
 func TestAllowedMethods(t *testing.T) {
 	handler := &Method{}
 	tests := []struct {
@@ -101,6 +184,7 @@ func TestAllowedMethods(t *testing.T) {
 				"DELETE",
 				"GET",
 				"HEAD",
+				"OPTIONS",
 				"PATCH",
 				"POST",
 				"PUT",
@@ -109,7 +193,7 @@ func TestAllowedMethods(t *testing.T) {
 		{
 			"empty",
 			&Method{},
-			[]string{},
+			[]string{"OPTIONS"},
 		},
 		{
 			"get as head",
@@ -120,6 +204,7 @@ func TestAllowedMethods(t *testing.T) {
 			[]string{
 				"GET",
 				"HEAD",
+				"OPTIONS",
 			},
 		},
 	}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"net/http"
 
+	"github.com/bhenderson/web/conditional"
 	"github.com/bhenderson/web/flush"
 	"github.com/bhenderson/web/head"
 	"github.com/bhenderson/web/log"
@@ -67,6 +68,12 @@ const (
 	CommonLog   = log.Common
 )
 
+// Conditional implements Middleware. See conditional.ConditionalMiddleware for
+// usage.
+func Conditional(next http.Handler) http.HandlerFunc {
+	return conditional.ConditionalMiddleware(next)
+}
+
 // Flush implements Middleware. See flush.FlushMiddleware for usage.
 func Flush(next http.Handler) http.HandlerFunc {
 	return flush.FlushMiddleware(next)
@@ -85,3 +92,18 @@ func Log(w io.Writer, t string) Middleware {
 func Session(secret, name string) Middleware {
 	return session.SessionMiddleware(secret, name)
 }
+
+// SessionCodec returns a Middleware. See session.CodecMiddleware for usage.
+func SessionCodec(c session.Codec, name string) Middleware {
+	return session.CodecMiddleware(c, name)
+}
+
+// SessionPolicy returns a Middleware. See session.Policy for usage.
+func SessionPolicy(p session.CookiePolicy, name string) Middleware {
+	return session.Policy(p, name)
+}
+
+// SessionStore returns a Middleware. See session.Middleware for usage.
+func SessionStore(c session.Config) Middleware {
+	return session.Middleware(c)
+}
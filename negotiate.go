@@ -0,0 +1,214 @@
+package web
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MediaHandler pairs a media type with the handler for it. See Produces and
+// Consumes.
+type MediaHandler struct {
+	// Type is a media type such as application/json. It may be a wildcard
+	// such as text/* or */*.
+	Type    string
+	Handler http.Handler
+}
+
+// Produces is an http.Handler that selects a handler by the Accept header of
+// the request (see NegotiateContentType). Handlers are listed in order of
+// preference, which decides ties. If none of the types is acceptable it
+// replies with 406 Not Acceptable.
+//
+// The Content-Type header is set to the selected type unless the type is a
+// wildcard or the header is already set, and Accept is added to Vary.
+//
+//	m := &web.Method{
+//		Get: web.Produces{
+//			{"text/html", html},
+//			{"application/json", json},
+//		},
+//	}
+type Produces []MediaHandler
+
+func (p Produces) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	offers := make([]string, len(p))
+	for i, mh := range p {
+		offers[i] = mh.Type
+	}
+
+	w.Header().Add("Vary", "Accept")
+
+	t := NegotiateContentType(r, offers...)
+	if t == "" {
+		NotAcceptable(w, r)
+		return
+	}
+
+	for _, mh := range p {
+		if mh.Type != t {
+			continue
+		}
+		if !strings.Contains(t, "*") && w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", t)
+		}
+		mh.Handler.ServeHTTP(w, r)
+		return
+	}
+}
+
+// Consumes is an http.Handler that selects the first handler whose type
+// matches the Content-Type header of the request (see MatchContentType). If
+// none does it replies with 415 Unsupported Media Type.
+type Consumes []MediaHandler
+
+func (c Consumes) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	types := make([]string, len(c))
+	for i, mh := range c {
+		types[i] = mh.Type
+	}
+
+	t := MatchContentType(r, types...)
+	if t == "" {
+		UnsupportedMediaType(w, r)
+		return
+	}
+
+	for _, mh := range c {
+		if mh.Type == t {
+			mh.Handler.ServeHTTP(w, r)
+			return
+		}
+	}
+}
+
+// NotAcceptable replies to the request with an HTTP 406 not acceptable error.
+func NotAcceptable(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "406 not acceptable", http.StatusNotAcceptable)
+}
+
+// UnsupportedMediaType replies to the request with an HTTP 415 unsupported
+// media type error.
+func UnsupportedMediaType(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "415 unsupported media type", http.StatusUnsupportedMediaType)
+}
+
+// NegotiateContentType returns the offer that best matches the Accept header
+// of r, or "" if none is acceptable.
+//
+// Each offer gets the q-value of the most specific media range that matches
+// it (rfc7231 5.3.2). The offer with the highest q-value wins, then the one
+// matched by the most specific range, then the first one. Parameters other
+// than q are ignored. A request without a valid Accept header accepts
+// anything.
+func NegotiateContentType(r *http.Request, offers ...string) string {
+	ranges := parseAccept(strings.Join(r.Header["Accept"], ","))
+	if len(ranges) == 0 {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	best, bestQ, bestSpec := "", 0.0, -1
+	for _, offer := range offers {
+		o := parseMediaType(offer)
+
+		q, spec := 0.0, -1
+		for _, ar := range ranges {
+			if ar.spec > spec && ar.matches(o) {
+				q, spec = ar.q, ar.spec
+			}
+		}
+
+		if q > bestQ || q == bestQ && q > 0 && spec > bestSpec {
+			best, bestQ, bestSpec = offer, q, spec
+		}
+	}
+	return best
+}
+
+// MatchContentType returns the first of types matching the Content-Type
+// header of r, or "" if none does. types may contain wildcards such as text/*.
+// A request without a Content-Type is treated as application/octet-stream
+// (rfc7231 3.1.1.5).
+func MatchContentType(r *http.Request, types ...string) string {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	mt, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return ""
+	}
+	actual := parseMediaType(mt)
+
+	for _, t := range types {
+		if parseMediaType(t).matches(actual) {
+			return t
+		}
+	}
+	return ""
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+	// spec is 0 for */*, 1 for type/* and 2 for type/subtype.
+	spec int
+}
+
+// parseMediaType parses a media type without parameters.
+func parseMediaType(s string) mediaRange {
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	m := mediaRange{q: 1}
+	m.typ, m.subtype = s, ""
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		m.typ, m.subtype = s[:i], s[i+1:]
+	}
+	switch {
+	case m.typ == "*":
+		m.spec = 0
+	case m.subtype == "*":
+		m.spec = 1
+	default:
+		m.spec = 2
+	}
+	return m
+}
+
+// matches reports whether m and o match, either of them may be a wildcard.
+func (m mediaRange) matches(o mediaRange) bool {
+	return (m.typ == "*" || o.typ == "*" || m.typ == o.typ) &&
+		(m.subtype == "*" || o.subtype == "*" || m.subtype == o.subtype)
+}
+
+// parseAccept parses an Accept header, skipping invalid media ranges.
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, s := range strings.Split(header, ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		mt, params, err := mime.ParseMediaType(s)
+		if err != nil || !strings.Contains(mt, "/") {
+			continue
+		}
+
+		m := parseMediaType(mt)
+		if v, ok := params["q"]; ok {
+			q, err := strconv.ParseFloat(v, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+			m.q = q
+		}
+		ranges = append(ranges, m)
+	}
+	return ranges
+}
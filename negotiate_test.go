@@ -0,0 +1,132 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateContentType(t *testing.T) {
+	browser := "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+	tests := []struct {
+		accept string
+		offers []string
+		exp    string
+	}{
+		{"", []string{"text/html", "application/json"}, "text/html"},
+		{"", nil, ""},
+		{"application/json", []string{"text/html", "application/json"}, "application/json"},
+		{browser, []string{"application/json", "text/html"}, "text/html"},
+		{browser, []string{"application/json", "text/plain"}, "application/json"},
+		{"application/json, */*", []string{"text/html", "application/json"}, "application/json"},
+		{"*/*", []string{"text/html", "application/json"}, "text/html"},
+		{"text/*;q=0.5, application/json;q=0.4", []string{"application/json", "text/plain"}, "text/plain"},
+		{"text/*, text/plain;q=0", []string{"text/plain", "text/csv"}, "text/csv"},
+		{"image/png", []string{"text/html", "application/json"}, ""},
+		{"APPLICATION/JSON", []string{"application/json"}, "application/json"},
+		{"text/html;level=1;q=0.2, application/json;q=0.3", []string{"text/html", "application/json"}, "application/json"},
+		{"text/html;q=2, application/json;q=bad", []string{"text/html", "application/json"}, "text/html"},
+		{"image/png", []string{"image/*"}, "image/*"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		assert.Equal(t, test.exp, NegotiateContentType(r, test.offers...), test.accept)
+	}
+}
+
+func TestMatchContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		types       []string
+		exp         string
+	}{
+		{"application/json", []string{"application/json"}, "application/json"},
+		{"application/json; charset=utf-8", []string{"text/*", "application/json"}, "application/json"},
+		{"text/csv", []string{"text/*", "text/csv"}, "text/*"},
+		{"", []string{"application/json"}, ""},
+		{"", []string{"application/octet-stream"}, "application/octet-stream"},
+		{"text/plain", []string{"*/*"}, "*/*"},
+		{"bad;;", []string{"*/*"}, ""},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/", nil)
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		assert.Equal(t, test.exp, MatchContentType(r, test.types...), test.contentType)
+	}
+}
+
+func TestProduces(t *testing.T) {
+	body := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, s)
+		}
+	}
+
+	m := &Method{
+		Get: Produces{
+			{"text/html", body("html")},
+			{"application/json", body("json")},
+		},
+	}
+
+	serve := func(accept string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("application/json")
+	assert.Equal(t, "json", w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+
+	w = serve("text/html;q=0.9, application/json;q=0.1")
+	assert.Equal(t, "html", w.Body.String())
+
+	w = serve("image/png")
+	assert.Equal(t, 406, w.Code)
+	assert.Equal(t, "406 not acceptable\n", w.Body.String())
+}
+
+func TestConsumes(t *testing.T) {
+	body := func(s string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, s)
+		}
+	}
+
+	m := &Method{
+		Post: Consumes{
+			{"application/json", body("json")},
+			{"application/x-www-form-urlencoded", body("form")},
+		},
+	}
+
+	serve := func(contentType string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/", nil)
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, "json", serve("application/json; charset=utf-8").Body.String())
+	assert.Equal(t, "form", serve("application/x-www-form-urlencoded").Body.String())
+
+	w := serve("text/xml")
+	assert.Equal(t, 415, w.Code)
+	assert.Equal(t, "415 unsupported media type\n", w.Body.String())
+}
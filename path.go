@@ -0,0 +1,116 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// PathPolicy decides what to do with a request whose URL.Path is not in its
+// canonical form (see CleanPath). It is used by Resource, router.Router and
+// api.Handler.WithPathPolicy.
+//
+// The trailing slash is part of the canonical form only if PathAddSlash or
+// PathStripSlash is set, so that /users and /users/ reach the same handler:
+//
+//	r.PathPolicy = web.PathRedirect | web.PathStripSlash
+type PathPolicy int
+
+const (
+	// PathStrict leaves the path alone. This is the default.
+	PathStrict PathPolicy = iota
+
+	// PathClean silently replaces the path with its canonical form.
+	PathClean
+
+	// PathRedirect redirects to the canonical form with 301 Moved Permanently
+	// for GET and HEAD requests, and 308 Permanent Redirect otherwise so the
+	// method and body are kept.
+	PathRedirect
+)
+
+// Trailing slash options, combined with PathClean or PathRedirect. Without
+// either they act like PathClean.
+const (
+	// PathAddSlash adds a trailing slash to every path.
+	PathAddSlash PathPolicy = 1 << (iota + 2)
+
+	// PathStripSlash removes the trailing slash from every path but /.
+	PathStripSlash
+)
+
+// pathMode masks the policy out of the trailing slash options.
+const pathMode = PathClean | PathRedirect
+
+// Apply applies the policy to r. It returns the request to continue with, or
+// nil if a redirect was written to w.
+//
+// The redirect goes to the canonical form of r.URL.Path. A handler behind
+// http.StripPrefix or router.Router.Mount must use ApplyPrefix instead, or
+// leave redirects to a policy of the outermost handler.
+func (p PathPolicy) Apply(w http.ResponseWriter, r *http.Request) *http.Request {
+	return p.ApplyPrefix(w, r, "")
+}
+
+// ApplyPrefix is like Apply for a request whose path had prefix stripped:
+// redirects go to prefix followed by the canonical path.
+func (p PathPolicy) ApplyPrefix(w http.ResponseWriter, r *http.Request, prefix string) *http.Request {
+	if p == PathStrict {
+		return r
+	}
+
+	clean := p.canonical(r.URL.Path)
+	if clean == r.URL.Path {
+		return r
+	}
+
+	u := *r.URL
+	u.Path, u.RawPath = clean, ""
+
+	if p&pathMode == PathRedirect {
+		code := http.StatusPermanentRedirect
+		if r.Method == "GET" || r.Method == "HEAD" {
+			code = http.StatusMovedPermanently
+		}
+		target := (&url.URL{Path: prefix + clean, RawQuery: r.URL.RawQuery}).String()
+		http.Redirect(w, r, target, code)
+		return nil
+	}
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = &u
+	return r2
+}
+
+// canonical returns CleanPath(p) with the trailing slash option of the policy
+// applied.
+func (p PathPolicy) canonical(urlPath string) string {
+	urlPath = CleanPath(urlPath)
+	switch {
+	case p&PathStripSlash != 0 && urlPath != "/":
+		urlPath = strings.TrimSuffix(urlPath, "/")
+	case p&PathAddSlash != 0 && !strings.HasSuffix(urlPath, "/"):
+		urlPath += "/"
+	}
+	return urlPath
+}
+
+// CleanPath returns the canonical form of p: rooted, without duplicate
+// slashes, . or .. elements. A trailing slash is kept, so /users and /users/
+// stay different paths (see PathParts for how Resource treats them, and
+// PathStripSlash).
+func CleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
@@ -0,0 +1,82 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path, exp string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"users", "/users"},
+		{"/users", "/users"},
+		{"/users/", "/users/"},
+		{"//users//1", "/users/1"},
+		{"/users/./1/", "/users/1/"},
+		{"/users/../../etc/passwd", "/etc/passwd"},
+		{"/users/..", "/"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.exp, CleanPath(test.path), test.path)
+	}
+}
+
+func TestPathPolicy(t *testing.T) {
+	var path string
+	var f http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+	}
+
+	tests := []struct {
+		policy       PathPolicy
+		method, url  string
+		code         int
+		path, target string
+	}{
+		{PathStrict, "GET", "/a//b", 200, "/a//b", ""},
+		{PathClean, "GET", "/a//b", 200, "/a/b", ""},
+		{PathClean, "GET", "/a/b/", 200, "/a/b/", ""},
+		{PathRedirect, "GET", "/a/../b?x=1", 301, "", "/b?x=1"},
+		{PathRedirect, "POST", "/a//b", 308, "", "/a/b"},
+		{PathRedirect, "POST", "/a/b", 200, "/a/b", ""},
+		{PathStripSlash, "GET", "/a//b/", 200, "/a/b", ""},
+		{PathClean | PathStripSlash, "GET", "/", 200, "/", ""},
+		{PathClean | PathAddSlash, "GET", "/a/b", 200, "/a/b/", ""},
+		{PathRedirect | PathStripSlash, "GET", "/a/b/?x=1", 301, "", "/a/b?x=1"},
+		{PathRedirect | PathAddSlash, "POST", "/a//b", 308, "", "/a/b/"},
+		{PathRedirect | PathAddSlash, "GET", "/a/b/", 200, "/a/b/", ""},
+	}
+
+	for _, test := range tests {
+		path = ""
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, test.url, nil)
+
+		if r = test.policy.Apply(w, r); r != nil {
+			f(w, r)
+		}
+
+		assert.Equal(t, test.code, w.Code, test.url)
+		assert.Equal(t, test.path, path, test.url)
+		assert.Equal(t, test.target, w.Header().Get("Location"), test.url)
+	}
+}
+
+func TestPathPolicy_ApplyPrefix(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "//42?x=1", nil)
+	assert.Nil(t, PathRedirect.ApplyPrefix(w, r, "/users"))
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/users/42?x=1", w.Header().Get("Location"))
+
+	// the prefix is not part of the path the handler sees
+	r = PathClean.ApplyPrefix(w, r, "/users")
+	assert.Equal(t, "/42", r.URL.Path)
+}
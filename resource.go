@@ -1,7 +1,11 @@
 package web
 
 import (
+	"context"
+	"encoding"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -18,6 +22,95 @@ func (rhf ResourceHandleFunc) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	rhf(id).ServeHTTP(w, r)
 }
 
+// ResourceHandleFuncOf is like ResourceHandleFunc, but the second path element
+// is parsed into T first (see ParseID), so
+//
+//	Show: web.ResourceHandleFuncOf[int64](func(id int64) http.Handler { ... })
+//
+// gets a number. A missing id replies with 404 Not Found and an id that
+// doesn't parse with 400 Bad Request. If the function returns nil, for
+// example because nothing has that id, the reply is 404 Not Found. A T that
+// ParseID doesn't support panics on the first id.
+type ResourceHandleFuncOf[T any] func(T) http.Handler
+
+func (rhf ResourceHandleFuncOf[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	paths := PathParts(r.URL.Path)
+	if len(paths) < 2 || paths[1] == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := ParseID[T](paths[1])
+	if err != nil {
+		http.Error(w, "400 bad request", http.StatusBadRequest)
+		return
+	}
+
+	h := rhf(id)
+	if h == nil {
+		http.NotFound(w, r)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
+
+// ParseID parses s into a T. T may be a string, any int or uint type, or a
+// type whose pointer implements encoding.TextUnmarshaler, such as UUID.
+// ParseID panics for other types, as that is a programming error.
+func ParseID[T any](s string) (T, error) {
+	var id T
+
+	switch p := any(&id).(type) {
+	case *string:
+		*p = s
+		return id, nil
+	case encoding.TextUnmarshaler:
+		err := p.UnmarshalText([]byte(s))
+		return id, err
+	case *int:
+		v, err := strconv.ParseInt(s, 10, strconv.IntSize)
+		*p = int(v)
+		return id, err
+	case *int8:
+		v, err := strconv.ParseInt(s, 10, 8)
+		*p = int8(v)
+		return id, err
+	case *int16:
+		v, err := strconv.ParseInt(s, 10, 16)
+		*p = int16(v)
+		return id, err
+	case *int32:
+		v, err := strconv.ParseInt(s, 10, 32)
+		*p = int32(v)
+		return id, err
+	case *int64:
+		v, err := strconv.ParseInt(s, 10, 64)
+		*p = v
+		return id, err
+	case *uint:
+		v, err := strconv.ParseUint(s, 10, strconv.IntSize)
+		*p = uint(v)
+		return id, err
+	case *uint8:
+		v, err := strconv.ParseUint(s, 10, 8)
+		*p = uint8(v)
+		return id, err
+	case *uint16:
+		v, err := strconv.ParseUint(s, 10, 16)
+		*p = uint16(v)
+		return id, err
+	case *uint32:
+		v, err := strconv.ParseUint(s, 10, 32)
+		*p = uint32(v)
+		return id, err
+	case *uint64:
+		v, err := strconv.ParseUint(s, 10, 64)
+		*p = v
+		return id, err
+	}
+	panic(fmt.Sprintf("web: can't parse an id into %T", id))
+}
+
 type Resource struct {
 	// Handlers for a resource.
 	// Index   -> GET /prefix/
@@ -41,7 +134,53 @@ type Resource struct {
 	MethodNotAllowed,
 
 	// Handler is called for /prefix/:id/
-	Handler,
+	Handler http.Handler
+
+	// Resources are nested below /prefix/:id/ by name, so with
+	//
+	//	users := &Resource{Resources: map[string]*Resource{"posts": posts}}
+	//
+	// /users/42/posts/7 is /posts/7 for posts. Each resource sees the path
+	// from its own name on; the ids of the resources above it are available
+	// through ResourceID. Paths below /prefix/:id/ that don't name a
+	// resource or a member action go to Handler.
+	Resources map[string]*Resource
+
+	// CollectionActions are served for /prefix/name, eg GET /orders/search,
+	// instead of Show and friends. MemberActions are served for
+	// /prefix/:id/name, eg POST /orders/42/cancel, with the id available
+	// like for Show. Each Method answers OPTIONS and 405 Method Not Allowed
+	// with its own methods.
+	CollectionActions map[string]*Method
+	MemberActions     map[string]*Method
+
+	// CORS is used for the index, the members and any actions that don't
+	// set their own. See Method.CORS.
+	CORS *CORS
+
+	// Validate is used for Show, Replace, Update and Delete. See
+	// Method.Validate.
+	Validate func(*http.Request) Validators
+
+	// Exists, if set, is called with the id before a member action or a
+	// nested resource is served. If it returns false the reply is NotFound.
+	Exists func(id string) bool
+
+	// PathPolicy is applied to the request path first. See PathPolicy. If
+	// Stripped is set, redirects go to the path with Prefix.
+	PathPolicy PathPolicy
+
+	// Prefix is the path the resource is served at, eg /api/v1/users.
+	// Without it the resource name must be the first segment of the path.
+	// With it the path must start with Prefix (http.ServeMux), or have had
+	// all of Prefix removed if Stripped is set (http.StripPrefix,
+	// router.Router.Mount). Prefix is replaced by its last segment, so
+	// handlers see /users/42 either way. Without Stripped, paths that don't
+	// start with Prefix are NotFound.
+	Prefix string
+
+	// Stripped is set if the path of the request no longer has Prefix.
+	Stripped bool
 
 	method,
 	index http.Handler
@@ -50,6 +189,27 @@ type Resource struct {
 func (rs *Resource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	buildResource(rs)
 
+	// a stripped path is made relative first, so that redirects of the
+	// policy can put the parent of Prefix back.
+	var parent string
+	if rs.Prefix != "" && rs.Stripped {
+		r = rs.relative(r)
+		parent = rs.parent()
+	}
+
+	if r = rs.PathPolicy.ApplyPrefix(w, r, parent); r == nil {
+		return
+	}
+
+	if rs.Prefix != "" && !rs.Stripped {
+		r2 := rs.relative(r)
+		if r2 == nil {
+			rs.NotFound.ServeHTTP(w, r)
+			return
+		}
+		r = r2
+	}
+
 	paths := PathParts(r.URL.Path)
 
 	// no resource id
@@ -58,7 +218,32 @@ func (rs *Resource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(paths) == 2 {
+		if m, ok := rs.CollectionActions[paths[1]]; ok {
+			rs.action(m).ServeHTTP(w, r)
+			return
+		}
+	}
+
+	r = withResourceID(r, paths[0], paths[1])
+
 	if len(paths) > 2 {
+		m, isAction := rs.MemberActions[paths[2]]
+		isAction = isAction && len(paths) == 3
+		child, isChild := rs.Resources[paths[2]]
+
+		if (isAction || isChild) && rs.Exists != nil && !rs.Exists(paths[1]) {
+			rs.NotFound.ServeHTTP(w, r)
+			return
+		}
+		if isAction {
+			rs.action(m).ServeHTTP(w, r)
+			return
+		}
+		if isChild {
+			child.ServeHTTP(w, trimSegments(r, 2))
+			return
+		}
 		if rs.Handler == nil {
 			rs.NotFound.ServeHTTP(w, r)
 		} else {
@@ -70,6 +255,99 @@ func (rs *Resource) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	rs.method.ServeHTTP(w, r)
 }
 
+// action returns m with the CORS of rs, unless it has its own.
+func (rs *Resource) action(m *Method) *Method {
+	if m.CORS != nil || rs.CORS == nil {
+		return m
+	}
+	mc := *m
+	mc.CORS = rs.CORS
+	return &mc
+}
+
+type resourceID struct {
+	name, id string
+}
+
+type resourceIDsKey struct{}
+
+func withResourceID(r *http.Request, name, id string) *http.Request {
+	ids, _ := r.Context().Value(resourceIDsKey{}).([]resourceID)
+	ids = append(ids[:len(ids):len(ids)], resourceID{name, id})
+	return r.WithContext(context.WithValue(r.Context(), resourceIDsKey{}, ids))
+}
+
+// ResourceID returns the id of the resource called name in the path of r, eg
+// ResourceID(r, "users") is "42" for /users/42/posts/7. name is the path
+// segment the resource was reached by. It returns "" if there is none. Use
+// ParseID for typed ids.
+func ResourceID(r *http.Request, name string) string {
+	ids, _ := r.Context().Value(resourceIDsKey{}).([]resourceID)
+	for i := len(ids) - 1; i >= 0; i-- {
+		if ids[i].name == name {
+			return ids[i].id
+		}
+	}
+	return ""
+}
+
+// relative returns a shallow copy of r with its path relative to the parent
+// of rs.Prefix, or nil if the path doesn't start with rs.Prefix. See
+// Resource.Prefix.
+func (rs *Resource) relative(r *http.Request) *http.Request {
+	prefix := "/" + strings.Trim(rs.Prefix, "/")
+	name := prefix[len(rs.parent()):]
+
+	path := r.URL.Path
+	if !rs.Stripped {
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			return nil
+		}
+		path = path[len(prefix):]
+	}
+	// http.StripPrefix with a trailing slash leaves no leading slash.
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+
+	u := *r.URL
+	u.Path, u.RawPath = name+path, ""
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = &u
+	return r2
+}
+
+// parent returns rs.Prefix without its last segment, eg /api/v1 for
+// /api/v1/users/.
+func (rs *Resource) parent() string {
+	prefix := "/" + strings.Trim(rs.Prefix, "/")
+	return prefix[:strings.LastIndexByte(prefix, '/')]
+}
+
+// trimSegments returns a shallow copy of r without the first n segments of
+// its path.
+func trimSegments(r *http.Request, n int) *http.Request {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	for ; n > 0; n-- {
+		i := strings.IndexByte(path, '/')
+		if i < 0 {
+			path = ""
+			break
+		}
+		path = path[i+1:]
+	}
+
+	u := *r.URL
+	u.Path, u.RawPath = "/"+path, ""
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = &u
+	return r2
+}
+
 func buildResource(rs *Resource) {
 	if rs.NotFound == nil {
 		rs.NotFound = http.NotFoundHandler()
@@ -79,6 +357,7 @@ func buildResource(rs *Resource) {
 			Get:              rs.Index,
 			Post:             rs.Create,
 			MethodNotAllowed: rs.MethodNotAllowed,
+			CORS:             rs.CORS,
 		}
 	}
 	if rs.method == nil {
@@ -88,19 +367,20 @@ func buildResource(rs *Resource) {
 			Put:              rs.Replace,
 			Delete:           rs.Delete,
 			MethodNotAllowed: rs.MethodNotAllowed,
+			CORS:             rs.CORS,
+			Validate:         rs.Validate,
 		}
 	}
 }
 
-// PathParts removes leading and trailing slash, then splits on slash
+// PathParts removes leading and trailing slash, then splits on slash, so
+// /users and /users/ both give [users]. It returns nil for "" and "/".
+// Duplicate slashes give empty parts; use PathClean to avoid them.
 func PathParts(path string) []string {
-	// removing leading /
-	if path[0] == '/' {
-		path = path[1:]
-	}
-	// remove trailing /
-	if path[len(path)-1] == '/' {
-		path = path[:len(path)-1]
+	path = strings.TrimPrefix(path, "/")
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		return nil
 	}
 	return strings.Split(path, "/")
 }
@@ -53,11 +53,301 @@ func TestResource(t *testing.T) {
 	assert.Equal(t, 405, c)
 }
 
+func TestResource_Nested(t *testing.T) {
+	handler := func(n string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s users=%s posts=%s %s",
+				r.Method, n, ResourceID(r, "users"), ResourceID(r, "posts"), r.URL.Path)
+		}
+	}
+
+	posts := &Resource{
+		Index: handler("posts index"),
+		Show:  handler("posts show"),
+	}
+	rs := &Resource{
+		Show:      handler("users show"),
+		Handler:   handler("users handler"),
+		Resources: map[string]*Resource{"posts": posts},
+		Exists: func(id string) bool {
+			return id != "0"
+		},
+	}
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/users/42", 200, "GET users show users=42 posts= /users/42"},
+		{"GET", "/users/42/posts/", 200, "GET posts index users=42 posts= /posts/"},
+		{"GET", "/users/42/posts", 200, "GET posts index users=42 posts= /posts"},
+		{"GET", "/users/42/posts/7", 200, "GET posts show users=42 posts=7 /posts/7"},
+		{"DELETE", "/users/42/posts/7", 405, "405 method not allowed\n"},
+		{"GET", "/users/42/posts/7/x", 404, "404 page not found\n"},
+		{"GET", "/users/0/posts/7", 404, "404 page not found\n"},
+		{"GET", "/users/42/comments", 200, "GET users handler users=42 posts= /users/42/comments"},
+	}
+
+	for _, test := range tests {
+		c, body := testResource(t, test.method, test.path, rs)
+		assert.Equal(t, test.code, c, test.path)
+		assert.Equal(t, test.body, body, test.path)
+	}
+}
+
+func TestResource_Actions(t *testing.T) {
+	handler := func(n string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s %s", r.Method, n, ResourceID(r, "orders"))
+		}
+	}
+
+	rs := &Resource{
+		Show: handler("show"),
+		CollectionActions: map[string]*Method{
+			"search": {Get: handler("search")},
+		},
+		MemberActions: map[string]*Method{
+			"cancel": {Post: handler("cancel")},
+		},
+		Exists: func(id string) bool {
+			return id != "0"
+		},
+	}
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/orders/search", 200, "GET search "},
+		{"GET", "/orders/search/", 200, "GET search "},
+		{"GET", "/orders/42", 200, "GET show 42"},
+		{"POST", "/orders/42/cancel", 200, "POST cancel 42"},
+		{"GET", "/orders/42/cancel", 405, "405 method not allowed\n"},
+		{"OPTIONS", "/orders/42/cancel", 200, ""},
+		{"POST", "/orders/search", 405, "405 method not allowed\n"},
+		{"POST", "/orders/0/cancel", 404, "404 page not found\n"},
+		{"POST", "/orders/42/cancel/now", 404, "404 page not found\n"},
+		{"POST", "/orders/42/refund", 404, "404 page not found\n"},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.path, nil)
+		rs.ServeHTTP(w, req)
+		assert.Equal(t, test.code, w.Code, test.method+" "+test.path)
+		assert.Equal(t, test.body, w.Body.String(), test.method+" "+test.path)
+	}
+
+	w := httptest.NewRecorder()
+	rs.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/orders/search", nil))
+	assert.Equal(t, "GET, OPTIONS", w.Header().Get("Allow"))
+
+	w = httptest.NewRecorder()
+	rs.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/orders/42/cancel", nil))
+	assert.Equal(t, "OPTIONS, POST", w.Header().Get("Allow"))
+}
+
+func TestResource_Prefix(t *testing.T) {
+	handler := func(n string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s", n, r.URL.Path)
+		}
+	}
+	show := ResourceHandleFunc(func(id string) http.Handler {
+		return handler("show " + id)
+	})
+
+	resource := func(prefix string, stripped bool) *Resource {
+		return &Resource{
+			Index:    handler("index"),
+			Show:     show,
+			Prefix:   prefix,
+			Stripped: stripped,
+		}
+	}
+
+	serve := func(h http.Handler, path string) string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/users/", resource("/api/v1/users/", false))
+
+	tests := []struct {
+		name string
+		h    http.Handler
+	}{
+		{"ServeMux", mux},
+		{"StripPrefix all", http.StripPrefix("/api/v1/users", resource("/api/v1/users", true))},
+		{"StripPrefix slash", http.StripPrefix("/api/v1/users/", resource("/api/v1/users", true))},
+		{"StripPrefix part", http.StripPrefix("/api", resource("/v1/users", false))},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, "show 42 /users/42", serve(test.h, "/api/v1/users/42"), test.name)
+		assert.Equal(t, "index /users/", serve(test.h, "/api/v1/users/"), test.name)
+		// the id is the name of the resource
+		assert.Equal(t, "show users /users/users", serve(test.h, "/api/v1/users/users"), test.name)
+	}
+
+	// v1 is not taken for the id
+	assert.Equal(t, "show 42 /users/42", serve(resource("/api/v1/users", false), "/api/v1/users/42"))
+
+	// paths outside of the prefix are not found
+	assert.Equal(t, "404 page not found\n", serve(resource("/api/v1/users", false), "/other/5"))
+	assert.Equal(t, "404 page not found\n", serve(resource("/api/v1/users", false), "/api/v1/usersx/5"))
+	assert.Equal(t, "404 page not found\n", serve(resource("/api/v1/users", false), "/42"))
+
+	// stripped to the id only
+	rs := resource("/users", true)
+	assert.Equal(t, "show users /users/users", serve(rs, "/users"))
+	assert.Equal(t, "index /users/", serve(rs, "/"))
+}
+
+func TestResource_Validate(t *testing.T) {
+	versions := map[string]string{"1": `"a"`}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	})
+	rs := &Resource{
+		Show:   ok,
+		Delete: ok,
+		Validate: func(r *http.Request) Validators {
+			return Validators{ETag: versions[ResourceID(r, "users")]}
+		},
+	}
+
+	serve := func(method, path, ifMatch string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("If-Match", ifMatch)
+		rs.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, 412, serve("DELETE", "/users/1", `"b"`))
+	assert.Equal(t, 200, serve("DELETE", "/users/1", `"a"`))
+	assert.Equal(t, 412, serve("DELETE", "/users/2", `*`))
+}
+
+func TestResource_PathPolicy(t *testing.T) {
+	rs := &Resource{
+		Index: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "index")
+		}),
+		Show: ResourceHandleFunc(func(id string) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "show ", id)
+			})
+		}),
+		PathPolicy: PathClean,
+	}
+
+	serve := func(path string) string {
+		w := httptest.NewRecorder()
+		// no ServeMux here, it would clean the path itself.
+		rs.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Body.String()
+	}
+
+	assert.Equal(t, "index", serve("/users//"))
+	assert.Equal(t, "show a", serve("/users//a/"))
+	assert.Equal(t, "index", serve("/users/a/.."))
+
+	// redirects keep the stripped prefix
+	rs.PathPolicy = PathRedirect
+	rs.Prefix, rs.Stripped = "/api/v1/users", true
+	w := httptest.NewRecorder()
+	http.StripPrefix("/api/v1/users", rs).ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/users//a", nil))
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "/api/v1/users/a", w.Header().Get("Location"))
+
+	// no leading slash is not a reason to redirect
+	w = httptest.NewRecorder()
+	http.StripPrefix("/api/v1/users/", rs).ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/users/a", nil))
+	assert.Equal(t, "show a", w.Body.String())
+}
+
+func TestResourceHandleFuncOf(t *testing.T) {
+	users := map[int64]string{1: "alice"}
+
+	show := ResourceHandleFuncOf[int64](func(id int64) http.Handler {
+		name, ok := users[id]
+		if !ok {
+			return nil
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, name)
+		})
+	})
+
+	rs := &Resource{Show: show}
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/1", 200, "alice"},
+		{"/users/2", 404, "404 page not found\n"},
+		{"/users/abc", 400, "400 bad request\n"},
+		{"/users/99999999999999999999", 400, "400 bad request\n"},
+	}
+
+	for _, test := range tests {
+		c, body := testResource(t, "GET", test.path, rs)
+		assert.Equal(t, test.code, c, test.path)
+		assert.Equal(t, test.body, body, test.path)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	s, err := ParseID[string]("abc")
+	assert.Nil(t, err)
+	assert.Equal(t, "abc", s)
+
+	i, err := ParseID[int]("-12")
+	assert.Nil(t, err)
+	assert.Equal(t, -12, i)
+
+	_, err = ParseID[uint8]("256")
+	assert.NotNil(t, err)
+
+	_, err = ParseID[uint]("-1")
+	assert.NotNil(t, err)
+
+	u, err := ParseID[UUID]("6BA7B810-9DAD-11D1-80B4-00C04FD430C8")
+	assert.Nil(t, err)
+	assert.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", u.String())
+
+	assert.PanicsWithValue(t, "web: can't parse an id into float64", func() {
+		ParseID[float64]("1.5")
+	})
+}
+
 func TestParseComponents(t *testing.T) {
 	tests := []struct {
 		path string
 		exp  []string
 	}{
+		{
+			"",
+			nil,
+		},
+		{
+			"/",
+			nil,
+		},
+		{
+			"/abc/",
+			[]string{"abc"},
+		},
 		{
 			"/abc",
 			[]string{"abc"},
@@ -0,0 +1,414 @@
+package router
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Handlers maps the names used in a config file to handlers. See ParseConfig.
+type Handlers map[string]http.Handler
+
+// ConfigError reports a problem in a config file.
+type ConfigError struct {
+	Line int
+	Msg  string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// ParseConfig builds a Router from an nginx like config.
+//
+//	# comments run to the end of the line
+//	location = / {
+//		handler index;
+//	}
+//	location ~* \.(gif|jpg|png)$ {
+//		handler images;
+//		methods GET HEAD;
+//	}
+//	location ^~ /admin/ {
+//		handler admin;
+//		host admin.example.com;
+//	}
+//
+// The modifier is optional and is one of the kinds accepted by
+// Router.Location. Inside a location block
+//
+//	handler name;       looks up name in handlers
+//	return code url;    uses Redirect(code, url) as the handler
+//	methods METHOD ...; see Route.Methods
+//	host name;          see Route.Host
+//	produces TYPE ...;  see Route.Produces
+//	consumes TYPE ...;  see Route.Consumes
+//	name name;          see Route.Name
+//	rewrite regexp replacement [flag]; see Route.Rewrite
+//
+// Each location needs either a handler or a return directive. rewrite may
+// also appear outside of a location, see Router.Rewrite.
+//
+// Arguments containing whitespace or one of {};# can be quoted with single or
+// double quotes. Errors are of type *ConfigError.
+func ParseConfig(rd io.Reader, handlers Handlers) (*Router, error) {
+	r := NewRouter()
+	if err := r.LoadConfig(rd, handlers); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// LoadConfig adds the locations in rd to r. See ParseConfig for the format.
+// Nothing is added to r if there is an error.
+func (r *Router) LoadConfig(rd io.Reader, handlers Handlers) error {
+	toks, err := scanConfig(rd)
+	if err != nil {
+		return err
+	}
+
+	p := &configParser{
+		toks:     toks,
+		handlers: handlers,
+		router:   r,
+		names:    make(map[string]bool),
+	}
+	rewrites, locs, err := p.parse()
+	if err != nil {
+		return err
+	}
+
+	for _, rw := range rewrites {
+		r.Rewrite(rw.pattern, rw.replacement, rw.flag)
+	}
+	for _, l := range locs {
+		rt := r.Location(l.kind, l.path, l.handler)
+		for _, rw := range l.rewrites {
+			rt.Rewrite(rw.pattern, rw.replacement, rw.flag)
+		}
+		if len(l.methods) > 0 {
+			rt.Methods(l.methods...)
+		}
+		if l.host != "" {
+			rt.Host(l.host)
+		}
+		if len(l.produces) > 0 {
+			rt.Produces(l.produces...)
+		}
+		if len(l.consumes) > 0 {
+			rt.Consumes(l.consumes...)
+		}
+		if l.name != "" {
+			rt.Name(l.name)
+		}
+	}
+	return nil
+}
+
+type configToken struct {
+	text string
+	line int
+	// quoted tokens are never treated as punctuation.
+	quoted bool
+}
+
+func (t configToken) is(s string) bool {
+	return !t.quoted && t.text == s
+}
+
+func scanConfig(rd io.Reader) ([]configToken, error) {
+	var toks []configToken
+
+	sc := bufio.NewScanner(rd)
+	line := 0
+	for sc.Scan() {
+		line++
+		s := sc.Text()
+
+		for i := 0; i < len(s); {
+			c := s[i]
+			switch {
+			case c == ' ' || c == '\t' || c == '\r':
+				i++
+			case c == '#':
+				i = len(s)
+			case c == '{' || c == '}' || c == ';':
+				toks = append(toks, configToken{text: string(c), line: line})
+				i++
+			case c == '"' || c == '\'':
+				end := strings.IndexByte(s[i+1:], c)
+				if end < 0 {
+					return nil, &ConfigError{line, "unterminated quoted string"}
+				}
+				toks = append(toks, configToken{text: s[i+1 : i+1+end], line: line, quoted: true})
+				i += end + 2
+			default:
+				j := i
+				for j < len(s) && !strings.ContainsRune(" \t\r#{};\"'", rune(s[j])) {
+					j++
+				}
+				toks = append(toks, configToken{text: s[i:j], line: line})
+				i = j
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return toks, nil
+}
+
+type configLocation struct {
+	kind, path string
+	handler    http.Handler
+	methods    []string
+	host, name string
+	produces   []string
+	consumes   []string
+	rewrites   []configRewrite
+}
+
+type configRewrite struct {
+	pattern, replacement, flag string
+}
+
+type configParser struct {
+	toks     []configToken
+	pos      int
+	handlers Handlers
+
+	// to check for duplicate names before anything is added.
+	router *Router
+	names  map[string]bool
+}
+
+func (p *configParser) next() (configToken, bool) {
+	if p.pos >= len(p.toks) {
+		return configToken{}, false
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	return t, true
+}
+
+// lastLine is used for errors at the end of the input.
+func (p *configParser) lastLine() int {
+	if len(p.toks) == 0 {
+		return 1
+	}
+	return p.toks[len(p.toks)-1].line
+}
+
+func (p *configParser) parse() (rewrites []configRewrite, locs []configLocation, err error) {
+	for {
+		t, ok := p.next()
+		if !ok {
+			return rewrites, locs, nil
+		}
+		switch {
+		case t.is("location"):
+			l, err := p.location(t.line)
+			if err != nil {
+				return nil, nil, err
+			}
+			locs = append(locs, l)
+		case t.is("rewrite"):
+			args, err := p.args()
+			if err != nil {
+				return nil, nil, err
+			}
+			rw, err := parseRewrite(t.line, args)
+			if err != nil {
+				return nil, nil, err
+			}
+			rewrites = append(rewrites, rw)
+		default:
+			return nil, nil, &ConfigError{t.line, fmt.Sprintf("unexpected %q, expecting location or rewrite", t.text)}
+		}
+	}
+}
+
+// args reads the arguments of a directive up to the ;
+func (p *configParser) args() (args []string, err error) {
+	for {
+		t, ok := p.next()
+		if !ok {
+			return nil, &ConfigError{p.lastLine(), "unexpected end of file, expecting ;"}
+		}
+		if t.is(";") {
+			return args, nil
+		}
+		if t.is("{") || t.is("}") {
+			return nil, &ConfigError{t.line, fmt.Sprintf("unexpected %q, expecting ;", t.text)}
+		}
+		args = append(args, t.text)
+	}
+}
+
+func parseRewrite(line int, args []string) (configRewrite, error) {
+	var rw configRewrite
+	switch len(args) {
+	case 3:
+		rw.flag = args[2]
+		if !rewriteFlags[rw.flag] {
+			return rw, &ConfigError{line, fmt.Sprintf("unknown rewrite flag %q", rw.flag)}
+		}
+		fallthrough
+	case 2:
+		rw.pattern, rw.replacement = args[0], args[1]
+	default:
+		return rw, &ConfigError{line, "rewrite requires a regexp, a replacement and an optional flag"}
+	}
+	if _, err := regexp.Compile(rw.pattern); err != nil {
+		return rw, &ConfigError{line, err.Error()}
+	}
+	return rw, nil
+}
+
+var configKinds = map[string]bool{
+	"=": true, "~": true, "~*": true, "^~": true, ":": true,
+}
+
+func (p *configParser) location(line int) (l configLocation, err error) {
+	// location [kind] path {
+	var args []configToken
+	for {
+		t, ok := p.next()
+		if !ok {
+			return l, &ConfigError{p.lastLine(), "unexpected end of file, expecting {"}
+		}
+		if t.is("{") {
+			break
+		}
+		if t.is("}") || t.is(";") {
+			return l, &ConfigError{t.line, fmt.Sprintf("unexpected %q, expecting {", t.text)}
+		}
+		args = append(args, t)
+	}
+
+	switch {
+	case len(args) == 1 && !(configKinds[args[0].text] && !args[0].quoted):
+		l.path = args[0].text
+	case len(args) == 2 && configKinds[args[0].text] && !args[0].quoted:
+		l.kind, l.path = args[0].text, args[1].text
+	case len(args) == 2:
+		return l, &ConfigError{line, fmt.Sprintf("unknown location modifier %q", args[0].text)}
+	default:
+		return l, &ConfigError{line, "location requires an optional modifier and a path"}
+	}
+
+	if err := checkLocation(l.kind, l.path); err != nil {
+		return l, &ConfigError{line, err.Error()}
+	}
+
+	for {
+		t, ok := p.next()
+		if !ok {
+			return l, &ConfigError{p.lastLine(), "unexpected end of file, expecting }"}
+		}
+		if t.is("}") {
+			break
+		}
+		if err := p.directive(t, &l); err != nil {
+			return l, err
+		}
+	}
+
+	if l.handler == nil {
+		return l, &ConfigError{line, fmt.Sprintf("location %q has no handler or return", l.path)}
+	}
+	return l, nil
+}
+
+func (p *configParser) directive(name configToken, l *configLocation) error {
+	args, err := p.args()
+	if err != nil {
+		return err
+	}
+
+	switch name.text {
+	case "handler":
+		if len(args) != 1 {
+			return &ConfigError{name.line, "handler requires exactly one name"}
+		}
+		if l.handler != nil {
+			return &ConfigError{name.line, "duplicate handler or return directive"}
+		}
+		h, ok := p.handlers[args[0]]
+		if !ok || h == nil {
+			return &ConfigError{name.line, fmt.Sprintf("unknown handler %q", args[0])}
+		}
+		l.handler = h
+	case "return":
+		if len(args) != 2 {
+			return &ConfigError{name.line, "return requires a code and a url"}
+		}
+		if l.handler != nil {
+			return &ConfigError{name.line, "duplicate handler or return directive"}
+		}
+		code, err := strconv.Atoi(args[0])
+		if err != nil || !redirectCodes[code] {
+			return &ConfigError{name.line, fmt.Sprintf("unsupported return code %q", args[0])}
+		}
+		l.handler = Redirect(code, args[1])
+	case "rewrite":
+		rw, err := parseRewrite(name.line, args)
+		if err != nil {
+			return err
+		}
+		l.rewrites = append(l.rewrites, rw)
+	case "methods":
+		if len(args) == 0 {
+			return &ConfigError{name.line, "methods requires at least one method"}
+		}
+		l.methods = append(l.methods, args...)
+	case "produces", "consumes":
+		if len(args) == 0 {
+			return &ConfigError{name.line, fmt.Sprintf("%s requires at least one media type", name.text)}
+		}
+		if name.text == "produces" {
+			l.produces = append(l.produces, args...)
+		} else {
+			l.consumes = append(l.consumes, args...)
+		}
+	case "host":
+		if len(args) != 1 {
+			return &ConfigError{name.line, "host requires exactly one name"}
+		}
+		l.host = args[0]
+	case "name":
+		if len(args) != 1 {
+			return &ConfigError{name.line, "name requires exactly one name"}
+		}
+		if p.names[args[0]] || p.router.hasName(args[0]) {
+			return &ConfigError{name.line, fmt.Sprintf("route name %q is already registered", args[0])}
+		}
+		p.names[args[0]] = true
+		l.name = args[0]
+	default:
+		return &ConfigError{name.line, fmt.Sprintf("unknown directive %q", name.text)}
+	}
+	return nil
+}
+
+// checkLocation reports the errors Router.Location would panic with.
+func checkLocation(kind, path string) (err error) {
+	switch kind {
+	case "~":
+		_, err = regexp.Compile(path)
+	case "~*":
+		_, err = regexp.Compile("(?i)" + path)
+	case ":":
+		defer func() {
+			if e := recover(); e != nil {
+				err = fmt.Errorf("%v", e)
+			}
+		}()
+		compilePattern(path)
+	}
+	return
+}
@@ -0,0 +1,130 @@
+package router
+
+import (
+	"strings"
+	"testing"
+)
+
+const testConfig = `
+# same as ExampleRouter_Location
+location = / {
+	handler A;
+}
+location / { handler B; }
+location /documents/ {
+	handler C;
+}
+location ^~ /images/ {
+	handler D;
+}
+location ~* "\.(gif|jpg|jpeg)$" {
+	handler E;
+	methods GET HEAD;
+}
+location : /users/:id {
+	handler F;
+	host api.example.com;
+	name user;
+}
+rewrite ^/old/(.*)$ /documents/$1 last;
+location ^~ /go/ {
+	rewrite ^/go/(.*)$ /images/$1 break;
+	handler D;
+}
+location : /people/:id {
+	return 301 /users/$id;
+}
+location = /feed {
+	handler A;
+	produces application/atom+xml;
+	consumes application/json;
+}
+`
+
+func TestParseConfig(t *testing.T) {
+	handlers := Handlers{}
+	for _, n := range []string{"A", "B", "C", "D", "E", "F"} {
+		handlers[n] = bodyHandler(n)
+	}
+
+	r, err := ParseConfig(strings.NewReader(testConfig), handlers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method, path string
+		code         int
+		exp          string
+	}{
+		{"GET", "/", 200, "A"},
+		{"GET", "/index.html", 200, "B"},
+		{"GET", "/documents/document.html", 200, "C"},
+		{"GET", "/images/1.gif", 200, "D"},
+		{"GET", "/documents/1.JPG", 200, "E"},
+		{"POST", "/documents/1.jpg", 405, "405 method not allowed\n"},
+		// host doesn't match
+		{"GET", "/users/1", 404, "404 page not found\n"},
+		{"GET", "/old/a.html", 200, "C"},
+		{"GET", "/go/a.png", 200, "D"},
+		{"GET", "/people/1", 301, "<a href=\"/users/1\">Moved Permanently</a>.\n\n"},
+		// no Content-Type
+		{"GET", "/feed", 415, "415 unsupported media type\n"},
+	}
+
+	for _, test := range tests {
+		w := serveMethod(r, test.method, test.path)
+		assertEqual(t, test.code, w.Code)
+		assertEqual(t, test.exp, w.Body.String())
+	}
+
+	u, err := r.URL("user", Params{{"id", "1"}})
+	assertEqual(t, nil, err)
+	assertEqual(t, "/users/1", u)
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	handlers := Handlers{"a": bodyHandler("a")}
+
+	tests := []struct {
+		config, exp string
+	}{
+		{"location / { handler b; }", `line 1: unknown handler "b"`},
+		{"\nlocation / {\n}", `line 2: location "/" has no handler or return`},
+		{"location / {\n\thandler a;\n\tfoo bar;\n}", `line 3: unknown directive "foo"`},
+		{"location / {\n\thandler a", `line 2: unexpected end of file, expecting ;`},
+		{"location / {\n\thandler a;\n", `line 2: unexpected end of file, expecting }`},
+		{"location / \n", `line 1: unexpected end of file, expecting {`},
+		{"server {}", `line 1: unexpected "server", expecting location or rewrite`},
+		{"location ~~ / { handler a; }", `line 1: unknown location modifier "~~"`},
+		{"location { handler a; }", `line 1: location requires an optional modifier and a path`},
+		{"location / { return 200 /; }", `line 1: unsupported return code "200"`},
+		{"location / { return 301; }", `line 1: return requires a code and a url`},
+		{"location / { return 301 /a; handler a; }", `line 1: duplicate handler or return directive`},
+		{"rewrite / /a stop;", `line 1: unknown rewrite flag "stop"`},
+		{"rewrite /;", `line 1: rewrite requires a regexp, a replacement and an optional flag`},
+		{"location / { handler a; rewrite ( /; }", "line 1: error parsing regexp: missing closing ): `(`"},
+		{"location / { handler a b; }", `line 1: handler requires exactly one name`},
+		{"location / { handler a; handler a; }", `line 1: duplicate handler or return directive`},
+		{"location / { methods; handler a; }", `line 1: methods requires at least one method`},
+		{"location / { handler a; host; }", `line 1: host requires exactly one name`},
+		{"location / { handler a; produces; }", `line 1: produces requires at least one media type`},
+		{"location / { handler 'a; }", `line 1: unterminated quoted string`},
+		{"location / { handler a; name x; }\nlocation = / {\n\thandler a;\n\tname x;\n}", `line 4: route name "x" is already registered`},
+		{"\n\nlocation ~ [bad { handler a; }", "line 3: error parsing regexp: missing closing ]: `[bad`"},
+		{"location : /a/*b/c { handler a; }", `line 1: pattern "/a/*b/c": "*b" must be the last segment`},
+	}
+
+	for _, test := range tests {
+		r := NewRouter()
+		err := r.LoadConfig(strings.NewReader(test.config), handlers)
+		if err == nil {
+			t.Errorf("%q: expected error %q", test.config, test.exp)
+			continue
+		}
+		assertEqual(t, test.exp, err.Error())
+		if _, ok := err.(*ConfigError); !ok {
+			t.Errorf("%q: expected *ConfigError got %T", test.config, err)
+		}
+	}
+}
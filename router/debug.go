@@ -0,0 +1,377 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// LocationInfo describes a registered location.
+type LocationInfo struct {
+	// Kind is the modifier the location would have in Router.Location.
+	// Regexps starting with (?i) are reported as "~*" without the flag.
+	Kind   string      `json:"kind"`
+	Path   string      `json:"path"`
+	Routes []RouteInfo `json:"routes"`
+}
+
+// RouteInfo describes a Route of a location.
+type RouteInfo struct {
+	Name     string   `json:"name,omitempty"`
+	Methods  []string `json:"methods,omitempty"`
+	Host     string   `json:"host,omitempty"`
+	Produces []string `json:"produces,omitempty"`
+	Consumes []string `json:"consumes,omitempty"`
+	Handler  string   `json:"handler"`
+}
+
+// Locations returns the registered locations in the order they are
+// considered: exact locations, prefix locations from longest to shortest, then
+// regexp and pattern locations in declaration order.
+func (r *Router) Locations() []LocationInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var exact, prefix []LocationInfo
+	r.tree.walk("", func(path string, n *node) {
+		if n.exact != nil {
+			exact = append(exact, n.exact.info())
+		}
+		if n.prefix != nil {
+			prefix = append(prefix, n.prefix.info())
+		}
+	})
+	sort.SliceStable(prefix, func(i, j int) bool {
+		return len(prefix[i].Path) > len(prefix[j].Path)
+	})
+
+	ls := append(exact, prefix...)
+	for _, l := range r.regexps {
+		ls = append(ls, l.info())
+	}
+	return ls
+}
+
+// Candidate is a location considered while matching a request.
+type Candidate struct {
+	Location LocationInfo `json:"location"`
+	Matched  bool         `json:"matched"`
+	Reason   string       `json:"reason"`
+	// Path is the path searched for, which differs from the request path
+	// after a rewrite.
+	Path string `json:"path"`
+}
+
+// RewriteInfo is a change of the request path while matching a request.
+type RewriteInfo struct {
+	// By is "path policy", "server" for the rewrites of the Router, or the
+	// path of the location whose rewrites applied.
+	By   string `json:"by"`
+	Path string `json:"path"`
+}
+
+// Explanation describes how a request was matched. See Router.Explain.
+type Explanation struct {
+	Method string `json:"method"`
+	Host   string `json:"host"`
+	Path   string `json:"path"`
+
+	// Rewrites lists the changes to the path, in order. Locations are
+	// searched again after a rewrite, so Candidates may list a location
+	// more than once.
+	Rewrites   []RewriteInfo `json:"rewrites,omitempty"`
+	Candidates []Candidate   `json:"candidates"`
+
+	// Redirect is the URL the request is redirected to by the PathPolicy
+	// or a rewrite, with the code in Status.
+	Redirect string `json:"redirect,omitempty"`
+
+	// Location is the winning location, or nil if there is none.
+	Location *LocationInfo `json:"location"`
+	// Route is the route that would handle the request. It is nil if no
+	// location matched, or none of its routes match the request.
+	Route *RouteInfo `json:"route"`
+	// Status is 405, 406 or 415 if a location matched but none of its
+	// routes match the method, Accept or Content-Type of the request. It is
+	// the code of Redirect if there is one, and 500 if rewrites cycle.
+	Status int `json:"status,omitempty"`
+	// Allowed lists the methods that would have matched, if the request
+	// would get a 405 Method Not Allowed.
+	Allowed []string `json:"allowed,omitempty"`
+	Params  Params   `json:"params,omitempty"`
+}
+
+// Explain reports every location considered for req, in the order the Router
+// considers them, and which one wins. The PathPolicy and rewrites are applied
+// like ServeHTTP does. req is not served.
+func (r *Router) Explain(req *http.Request) *Explanation {
+	e := &Explanation{
+		Method: req.Method,
+		Host:   req.Host,
+		Path:   req.URL.Path,
+	}
+	rw := &redirectWriter{header: http.Header{}}
+
+	path := req.URL.Path
+	if req = r.PathPolicy.ApplyPrefix(rw, req, MountPrefix(req)); req == nil {
+		e.Redirect, e.Status = rw.header.Get("Location"), rw.code
+		return e
+	}
+	if req.URL.Path != path {
+		e.Rewrites = append(e.Rewrites, RewriteInfo{"path policy", req.URL.Path})
+	}
+
+	req, result := r.explainRewrites(e, rw, req, nil, "server")
+	if result == rewriteRedirect {
+		return e
+	}
+
+	var h *locationHandler
+	var m routeMatch
+
+	for i := 0; ; i++ {
+		h, m = r.explainSearch(e, req)
+		if m.route == nil {
+			break
+		}
+
+		req, result = r.explainRewrites(e, rw, req, m.route, h.location)
+		if result == rewriteRedirect {
+			return e
+		}
+		if result != rewriteSearch {
+			break
+		}
+		if i == maxRewrites {
+			e.Status = http.StatusInternalServerError
+			return e
+		}
+	}
+
+	if h == nil {
+		return e
+	}
+
+	// break keeps the location, but its params come from the new path.
+	if result == rewriteBreak {
+		if l := h.match(req); l != nil {
+			h = l
+		}
+	}
+
+	info := h.info()
+	e.Location = &info
+	e.Params = h.params()
+
+	if m.route != nil {
+		ri := m.route.info()
+		e.Route = &ri
+	}
+	e.Status = m.status
+	e.Allowed = m.allowed
+	return e
+}
+
+// explainRewrites applies the rewrites of rt, or of r if rt is nil, and
+// records them in e.
+func (r *Router) explainRewrites(e *Explanation, rw *redirectWriter, req *http.Request, rt *Route, by string) (*http.Request, rewriteResult) {
+	req2, result := applyRewrites(rw, req, r.rules(rt))
+	switch {
+	case result == rewriteRedirect:
+		e.Redirect, e.Status = rw.header.Get("Location"), rw.code
+	case result != rewriteNone:
+		e.Rewrites = append(e.Rewrites, RewriteInfo{by, req2.URL.Path})
+	}
+	return req2, result
+}
+
+// explainSearch is like handler, but records the candidates in e.
+func (r *Router) explainSearch(e *Explanation, req *http.Request) (*locationHandler, routeMatch) {
+	path := req.URL.Path
+	return r.handler(req, func(l *locationHandler, matched bool, reason string) {
+		e.Candidates = append(e.Candidates, Candidate{l.info(), matched, reason, path})
+	})
+}
+
+// redirectWriter records a redirect written by Explain, which doesn't serve
+// the request.
+type redirectWriter struct {
+	header http.Header
+	code   int
+}
+
+func (w *redirectWriter) Header() http.Header         { return w.header }
+func (w *redirectWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *redirectWriter) WriteHeader(code int)        { w.code = code }
+
+// DebugHandler returns an http.Handler that lists the locations of r. If the
+// request has a path query parameter, it explains how a request for that path
+// would be matched instead. The method and host query parameters default to
+// GET and the request's host.
+//
+// The response is JSON if the format query parameter is "json" or the Accept
+// header asks for application/json, and plain text otherwise.
+func (r *Router) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+
+		var v interface{}
+		if path := q.Get("path"); path != "" {
+			method := q.Get("method")
+			if method == "" {
+				method = "GET"
+			}
+			er, err := http.NewRequest(method, path, nil)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			er.Host = req.Host
+			if host := q.Get("host"); host != "" {
+				er.Host = host
+			}
+			v = r.Explain(er)
+		} else {
+			v = r.Locations()
+		}
+
+		if q.Get("format") == "json" || strings.Contains(req.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(v)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		switch x := v.(type) {
+		case []LocationInfo:
+			writeLocations(w, x)
+		case *Explanation:
+			writeExplanation(w, x)
+		}
+	})
+}
+
+func writeLocations(w io.Writer, ls []LocationInfo) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tPATH\tMETHODS\tHOST\tNAME\tHANDLER")
+	for _, l := range ls {
+		for _, rt := range l.Routes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				dash(l.Kind), l.Path, dash(strings.Join(rt.Methods, ",")),
+				dash(rt.Host), dash(rt.Name), rt.Handler)
+		}
+	}
+	tw.Flush()
+}
+
+func writeExplanation(w io.Writer, e *Explanation) {
+	fmt.Fprintf(w, "%s %s (host %s)\n\n", e.Method, e.Path, dash(e.Host))
+
+	for _, rw := range e.Rewrites {
+		fmt.Fprintf(w, "rewritten to %s by %s\n", rw.Path, rw.By)
+	}
+	if len(e.Rewrites) > 0 {
+		fmt.Fprintln(w)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tKIND\tPATH\tREASON")
+	for _, c := range e.Candidates {
+		mark := ""
+		if c.Matched {
+			mark = "*"
+		}
+		reason := c.Reason
+		if c.Path != e.Path {
+			reason += " (for " + c.Path + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", mark, dash(c.Location.Kind), c.Location.Path, reason)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	switch {
+	case e.Redirect != "":
+		fmt.Fprintf(w, "redirect to %s: %d\n", e.Redirect, e.Status)
+	case e.Status == http.StatusInternalServerError:
+		fmt.Fprintln(w, "rewrite cycle: 500")
+	case e.Location == nil:
+		fmt.Fprintln(w, "no location matched: 404")
+	case e.Route != nil:
+		fmt.Fprintf(w, "location %s %s, handler %s\n", dash(e.Location.Kind), e.Location.Path, e.Route.Handler)
+	case e.Status == http.StatusMethodNotAllowed:
+		fmt.Fprintf(w, "location %s %s, method not allowed: 405 (Allow: %s)\n",
+			dash(e.Location.Kind), e.Location.Path, strings.Join(e.Allowed, ", "))
+	case e.Status != 0:
+		fmt.Fprintf(w, "location %s %s, %s: %d\n",
+			dash(e.Location.Kind), e.Location.Path, strings.ToLower(http.StatusText(e.Status)), e.Status)
+	default:
+		fmt.Fprintf(w, "location %s %s, no route for host: 404\n", dash(e.Location.Kind), e.Location.Path)
+	}
+	if len(e.Params) > 0 {
+		fmt.Fprintf(w, "params %v\n", e.Params)
+	}
+}
+
+// dash is used for empty columns.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (h *locationHandler) info() LocationInfo {
+	l := LocationInfo{Path: h.location}
+	switch {
+	case h.exact:
+		l.Kind = "="
+	case h.pattern != nil:
+		l.Kind, l.Path = ":", h.pattern.path
+	case h.regexp != nil:
+		l.Kind, l.Path = "~", h.regexp.String()
+		if strings.HasPrefix(l.Path, "(?i)") {
+			l.Kind, l.Path = "~*", l.Path[len("(?i)"):]
+		}
+	case h.noRegexs:
+		l.Kind = "^~"
+	}
+
+	for _, rt := range h.routes {
+		l.Routes = append(l.Routes, rt.info())
+	}
+	return l
+}
+
+func (r *Route) info() RouteInfo {
+	return RouteInfo{
+		Name:     r.name,
+		Methods:  append([]string(nil), r.methods...),
+		Host:     r.host,
+		Produces: append([]string(nil), r.produces...),
+		Consumes: append([]string(nil), r.consumes...),
+		Handler:  handlerName(r.handler),
+	}
+}
+
+// handlerName returns the function name for HandlerFuncs and the type name
+// for anything else.
+func handlerName(h http.Handler) string {
+	if m, ok := h.(*mountHandler); ok {
+		return "mount " + handlerName(m.handler)
+	}
+	if f, ok := h.(http.HandlerFunc); ok {
+		if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+			return fn.Name()
+		}
+	}
+	return fmt.Sprintf("%T", h)
+}
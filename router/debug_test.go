@@ -0,0 +1,159 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bhenderson/web"
+)
+
+func debugRouter() *Router {
+	r := NewRouter()
+	r.Location("=", "/", bodyHandler("A")).Name("home")
+	r.Location("", "/", bodyHandler("B"))
+	r.Location("", "/documents/", bodyHandler("C"))
+	r.Location("^~", "/images/", bodyHandler("D"))
+	r.Location("~*", `\.(?P<ext>gif|jpg|jpeg)$`, bodyHandler("E")).Methods("GET")
+	r.Location(":", "/users/:id", bodyHandler("F")).Host("api.example.com")
+	return r
+}
+
+func TestLocations(t *testing.T) {
+	var act []string
+	for _, l := range debugRouter().Locations() {
+		act = append(act, l.Kind+" "+l.Path)
+	}
+
+	assertEqual(t, []string{
+		"= /",
+		" /documents/",
+		"^~ /images/",
+		" /",
+		`~* \.(?P<ext>gif|jpg|jpeg)$`,
+		": /users/:id",
+	}, act)
+}
+
+func TestExplain(t *testing.T) {
+	r := debugRouter()
+
+	req, _ := http.NewRequest("POST", "/documents/1.jpg", nil)
+	e := r.Explain(req)
+
+	var act []string
+	for _, c := range e.Candidates {
+		act = append(act, c.Location.Path+": "+c.Reason)
+	}
+	assertEqual(t, []string{
+		"/documents/: longest prefix match, remembered while checking regexps",
+		"/: prefix match, not the longest",
+		`\.(?P<ext>gif|jpg|jpeg)$: first regexp match, stop searching`,
+	}, act)
+	assertEqual(t, `\.(?P<ext>gif|jpg|jpeg)$`, e.Location.Path)
+	assertEqual(t, (*RouteInfo)(nil), e.Route)
+	assertEqual(t, []string{"GET", "OPTIONS"}, e.Allowed)
+	assertEqual(t, Params{{"ext", "jpg"}}, e.Params)
+
+	req, _ = http.NewRequest("GET", "/images/1.gif", nil)
+	e = r.Explain(req)
+	assertEqual(t, "/images/", e.Location.Path)
+	assertEqual(t, "router.bodyHandler", e.Route.Handler)
+	assertEqual(t, 2, len(e.Candidates))
+
+	req, _ = http.NewRequest("GET", "/users/1", nil)
+	e = r.Explain(req)
+	assertEqual(t, ": /users/:id", e.Location.Kind+" "+e.Location.Path)
+	assertEqual(t, (*RouteInfo)(nil), e.Route)
+	assertEqual(t, []string(nil), e.Allowed)
+
+	// the slash redirect of a mount is a candidate like any other location
+	r.Mount("/api", NewRouter())
+	req, _ = http.NewRequest("GET", "/api", nil)
+	e = r.Explain(req)
+	assertEqual(t, "mount without trailing slash, redirect", e.Candidates[0].Reason)
+	assertEqual(t, "= /api", e.Location.Kind+" "+e.Location.Path)
+}
+
+func TestExplain_Rewrites(t *testing.T) {
+	r := NewRouter()
+	r.Rewrite("^/old$", "/x", "last")
+	r.Location("=", "/x", bodyHandler("X"))
+	r.Location("", "/legacy/", bodyHandler("L")).Rewrite(`^/legacy/(.*)$`, "/items/$1", "last")
+	r.Location(":", "/items/:id", bodyHandler("I"))
+	r.Location("=", "/loop", bodyHandler("P")).Rewrite("^/loop$", "/loop", "")
+	r.Location("=", "/moved", bodyHandler("M")).Rewrite("^/moved$", "/x", "permanent")
+
+	req, _ := http.NewRequest("GET", "/old", nil)
+	e := r.Explain(req)
+	assertEqual(t, "/x", e.Location.Path)
+	assertEqual(t, []RewriteInfo{{"server", "/x"}}, e.Rewrites)
+	assertEqual(t, "/x", e.Candidates[0].Path)
+
+	req, _ = http.NewRequest("GET", "/legacy/1", nil)
+	e = r.Explain(req)
+	assertEqual(t, "/items/:id", e.Location.Path)
+	assertEqual(t, Params{{"id", "1"}}, e.Params)
+	assertEqual(t, []RewriteInfo{{"/legacy/", "/items/1"}}, e.Rewrites)
+	var act []string
+	for _, c := range e.Candidates {
+		act = append(act, c.Path+" "+c.Location.Path)
+	}
+	assertEqual(t, []string{"/legacy/1 /legacy/", "/legacy/1 /items/:id", "/items/1 /items/:id"}, act)
+
+	req, _ = http.NewRequest("GET", "/moved", nil)
+	e = r.Explain(req)
+	assertEqual(t, "/x", e.Redirect)
+	assertEqual(t, http.StatusMovedPermanently, e.Status)
+
+	req, _ = http.NewRequest("GET", "/loop", nil)
+	e = r.Explain(req)
+	assertEqual(t, http.StatusInternalServerError, e.Status)
+	assertEqual(t, (*LocationInfo)(nil), e.Location)
+
+	r.PathPolicy = web.PathRedirect
+	req, _ = http.NewRequest("GET", "/a/../old", nil)
+	e = r.Explain(req)
+	assertEqual(t, "/old", e.Redirect)
+	assertEqual(t, http.StatusMovedPermanently, e.Status)
+	assertEqual(t, 0, len(e.Candidates))
+
+	r.PathPolicy = web.PathClean
+	e = r.Explain(req)
+	assertEqual(t, "/x", e.Location.Path)
+	assertEqual(t, []RewriteInfo{{"path policy", "/old"}, {"server", "/x"}}, e.Rewrites)
+}
+
+func TestDebugHandler(t *testing.T) {
+	h := debugRouter().DebugHandler()
+
+	get := func(url, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/debug/routes", "")
+	assertEqual(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	lines := strings.Split(w.Body.String(), "\n")
+	assertEqual(t, []string{"KIND", "PATH", "METHODS", "HOST", "NAME", "HANDLER"}, strings.Fields(lines[0]))
+	assertEqual(t, []string{"=", "/", "-", "-", "home", "router.bodyHandler"}, strings.Fields(lines[1]))
+
+	w = get("/debug/routes?path=/index.html", "")
+	if !strings.Contains(w.Body.String(), "location - /, handler router.bodyHandler\n") {
+		t.Errorf("unexpected explanation:\n%s", w.Body)
+	}
+
+	w = get("/debug/routes?path=/users/1&host=api.example.com", "application/json")
+	assertEqual(t, "application/json", w.Header().Get("Content-Type"))
+	var e Explanation
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatal(err)
+	}
+	assertEqual(t, "api.example.com", e.Route.Host)
+	assertEqual(t, Params{{"id", "1"}}, e.Params)
+}
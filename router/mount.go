@@ -0,0 +1,109 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Mount serves h for every path below prefix, with prefix stripped from the
+// request's URL.Path. h is usually another Router, which then only needs to
+// know about its own paths:
+//
+//	users := router.NewRouter()
+//	users.Location("=", "/", list)
+//	users.Location(":", "/:id", show)
+//
+//	r.Mount("/users", users) // GET /users/42 is GET /:id for users
+//
+// The trailing slash of prefix is optional; the location is always a ^~
+// prefix location for prefix + "/", so regexps of r don't get in the way.
+// The child sees "/" for prefix + "/". The path before any mount is available
+// through OriginalPath, and the stripped part through MountPrefix.
+//
+// Like http.ServeMux, prefix without the trailing slash is redirected to
+// prefix + "/", unless there is an exact location for it, whether added
+// before or after Mount.
+func (r *Router) Mount(prefix string, h http.Handler) *Route {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix != "" && prefix[0] != '/' {
+		panic(fmt.Sprintf("mount prefix %q must begin with /", prefix))
+	}
+	if prefix != "" {
+		r.addSlashRedirect(prefix)
+	}
+	return r.LocationPrefix(prefix+"/", &mountHandler{prefix, h})
+}
+
+// addSlashRedirect makes location return a redirect to prefix + "/" for
+// prefix, if there is no exact location for it.
+func (r *Router) addSlashRedirect(prefix string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.slashes == nil {
+		r.slashes = make(map[string]*locationHandler)
+	}
+	l := &locationHandler{location: prefix, exact: true}
+	l.addRoute(r, http.HandlerFunc(redirectSlash))
+	r.slashes[prefix] = l
+}
+
+// redirectSlash redirects to the path of req with a trailing slash.
+func redirectSlash(w http.ResponseWriter, req *http.Request) {
+	u := *req.URL
+	u.Path, u.RawPath = OriginalPath(req)+"/", ""
+	u.Scheme, u.Host = "", ""
+	http.Redirect(w, req, u.String(), http.StatusMovedPermanently)
+}
+
+type mountHandler struct {
+	prefix  string
+	handler http.Handler
+}
+
+type mount struct {
+	original, prefix string
+}
+
+func (m *mountHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+
+	mt := mount{original: req.URL.Path, prefix: m.prefix}
+	if parent, ok := ctx.Value(mountKey).(mount); ok {
+		mt.original = parent.original
+		mt.prefix = parent.prefix + m.prefix
+	}
+
+	r2 := req.WithContext(context.WithValue(ctx, mountKey, mt))
+	u := *req.URL
+	u.Path = strings.TrimPrefix(req.URL.Path, m.prefix)
+	if u.RawPath != "" {
+		// if the prefix was escaped, let URL.EscapedPath work it out.
+		rp := strings.TrimPrefix(u.RawPath, m.prefix)
+		if len(rp) == len(u.RawPath) {
+			rp = ""
+		}
+		u.RawPath = rp
+	}
+	r2.URL = &u
+
+	m.handler.ServeHTTP(w, r2)
+}
+
+// OriginalPath returns the URL.Path of req before any Router.Mount stripped
+// its prefix.
+func OriginalPath(req *http.Request) string {
+	if mt, ok := req.Context().Value(mountKey).(mount); ok {
+		return mt.original
+	}
+	return req.URL.Path
+}
+
+// MountPrefix returns everything stripped from req's URL.Path by Router.Mount,
+// or "" if req was not served by a mounted handler.
+func MountPrefix(req *http.Request) string {
+	mt, _ := req.Context().Value(mountKey).(mount)
+	return mt.prefix
+}
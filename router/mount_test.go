@@ -0,0 +1,160 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bhenderson/web"
+)
+
+func TestMount(t *testing.T) {
+	show := func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s %s %v",
+			req.URL.Path, OriginalPath(req), MountPrefix(req), ParamsFromContext(req.Context()))
+	}
+
+	users := NewRouter()
+	users.LocationFunc("=", "/", show)
+	users.LocationFunc(":", "/:id", show)
+
+	r := NewRouter()
+	r.Mount("/users", users)
+	r.Mount("/static/", http.StripPrefix("/", http.HandlerFunc(show)))
+	r.Location("~", `^/users/`, bodyHandler("regexp"))
+
+	tests := []struct {
+		path, exp string
+	}{
+		{"/users/", "/ /users/ /users []"},
+		{"/users/42", "/42 /users/42 /users [{id 42}]"},
+		{"/static/a/b", "a/b /static/a/b /static []"},
+	}
+
+	for _, test := range tests {
+		assertEqual(t, test.exp, serve(r, test.path).Body.String())
+	}
+
+	w := serve(r, "/users")
+	assertEqual(t, 301, w.Code)
+	assertEqual(t, "/users/", w.Header().Get("Location"))
+	assertEqual(t, 404, serve(r, "/usersx").Code)
+
+	// the query is kept
+	w = serve(r, "/users?page=2")
+	assertEqual(t, "/users/?page=2", w.Header().Get("Location"))
+
+	// an exact location of its own wins
+	r = NewRouter()
+	r.Location("=", "/users", bodyHandler("exact"))
+	r.Mount("/users", users)
+	assertEqual(t, "exact", serve(r, "/users").Body.String())
+
+	// also if added after Mount
+	r = NewRouter()
+	r.Mount("/users", users)
+	r.Location("=", "/users", bodyHandler("exact"))
+	assertEqual(t, "exact", serve(r, "/users").Body.String())
+	assertEqual(t, 1, len(r.Locations()[0].Routes))
+
+	// redirects of the child keep the prefix
+	child := NewRouter()
+	child.PathPolicy = web.PathRedirect
+	child.Location(":", "/:id", bodyHandler("show"))
+	r = NewRouter()
+	r.Mount("/users", child)
+	w = serve(r, "/users//42")
+	assertEqual(t, 301, w.Code)
+	assertEqual(t, "/users/42", w.Header().Get("Location"))
+}
+
+func TestMountNested(t *testing.T) {
+	show := func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s %s %s %v",
+			req.URL.Path, OriginalPath(req), MountPrefix(req), ParamsFromContext(req.Context()))
+	}
+
+	comments := NewRouter()
+	comments.LocationFunc(":", "/:comment", show)
+
+	posts := NewRouter()
+	posts.Mount("/posts", comments)
+
+	r := NewRouter()
+	r.Location("~", `^/api/`, bodyHandler("regexp"))
+	r.Mount("/api/v1", posts)
+
+	req := httptest.NewRequest("GET", "/api/v1/posts/7", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assertEqual(t, "/7 /api/v1/posts/7 /api/v1/posts [{comment 7}]", w.Body.String())
+
+	// the redirect has the full path
+	w = serve(r, "/api/v1/posts")
+	assertEqual(t, 301, w.Code)
+	assertEqual(t, "/api/v1/posts/", w.Header().Get("Location"))
+}
+
+func TestMountParams(t *testing.T) {
+	child := NewRouter()
+	child.LocationFunc(":", "/:uid/posts/:post", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, child.Params(req))
+	})
+
+	parent := NewRouter()
+	parent.Location(":", "/users/:id/*rest", http.StripPrefix("/users", child))
+
+	// a mounted router appends its params to its parent's.
+	w := httptest.NewRecorder()
+	parent.ServeHTTP(w, httptest.NewRequest("GET", "/users/42/posts/7", nil))
+	assertEqual(t, "[{id 42} {rest posts/7} {uid 42} {post 7}]", w.Body.String())
+}
+
+func TestMountPanic(t *testing.T) {
+	assertPanic(t, `mount prefix "users" must begin with /`, func() {
+		NewRouter().Mount("users", nil)
+	})
+}
+
+func TestMountVersions(t *testing.T) {
+	v1 := NewRouter()
+	v1.Location("=", "/users", bodyHandler("v1 users"))
+	v2 := NewRouter()
+	v2.Location("=", "/users", bodyHandler("v2 users"))
+
+	r := NewRouter()
+	r.Mount("/api", &web.Versions{
+		Versions: []web.Version{
+			{Name: "1", Handler: v1},
+			{Name: "2", Handler: v2},
+		},
+		Default: "1",
+	})
+
+	w := serve(r, "/api/v2/users")
+	assertEqual(t, "v2 users", w.Body.String())
+	w = serve(r, "/api/users")
+	assertEqual(t, "v1 users", w.Body.String())
+}
+
+func TestMountResource(t *testing.T) {
+	rs := &web.Resource{
+		Index: bodyHandler("index"),
+		Show: web.ResourceHandleFunc(func(id string) http.Handler {
+			return bodyHandler("show " + id)
+		}),
+		Prefix:   "/api/v1/users",
+		Stripped: true,
+	}
+
+	r := NewRouter()
+	r.Mount("/api/v1/users", rs)
+
+	w := serve(r, "/api/v1/users/42")
+	assertEqual(t, "show 42", w.Body.String())
+	w = serve(r, "/api/v1/users/")
+	assertEqual(t, "index", w.Body.String())
+	w = serve(r, "/api/v1/users/users")
+	assertEqual(t, "show users", w.Body.String())
+}
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pattern matches paths with named segments such as /users/:id/posts/:post.
+//
+//	:name matches a single, non-empty path segment
+//	*name matches the rest of the path (may be empty). Must be last.
+//
+// Everything else must match literally. Patterns are compiled into a list of
+// parts once, so matching is a single pass over the path.
+type pattern struct {
+	path  string
+	parts []patternPart
+	names []string
+}
+
+type patternPart struct {
+	// literal is matched as is, otherwise name is captured.
+	literal  string
+	name     string
+	catchAll bool
+}
+
+// compilePattern panics if path is not a valid pattern.
+func compilePattern(path string) *pattern {
+	if len(path) == 0 || path[0] != '/' {
+		panic(fmt.Sprintf("pattern %q must begin with /", path))
+	}
+
+	p := &pattern{path: path}
+	segments := strings.Split(path[1:], "/")
+	lit := "/"
+
+	for i, seg := range segments {
+		if len(seg) == 0 || (seg[0] != ':' && seg[0] != '*') {
+			if strings.ContainsAny(seg, ":*") {
+				panic(fmt.Sprintf("pattern %q: parameters must span a whole segment", path))
+			}
+			lit += seg
+			if i < len(segments)-1 {
+				lit += "/"
+			}
+			continue
+		}
+
+		name := seg[1:]
+		if len(name) == 0 {
+			panic(fmt.Sprintf("pattern %q: parameter in segment %d has no name", path, i+1))
+		}
+		if strings.ContainsAny(name, ":*") {
+			panic(fmt.Sprintf("pattern %q: invalid parameter name %q", path, name))
+		}
+		for _, n := range p.names {
+			if n == name {
+				panic(fmt.Sprintf("pattern %q: duplicate parameter %q", path, name))
+			}
+		}
+
+		catchAll := seg[0] == '*'
+		if catchAll && i != len(segments)-1 {
+			panic(fmt.Sprintf("pattern %q: %q must be the last segment", path, seg))
+		}
+
+		if lit != "" {
+			p.parts = append(p.parts, patternPart{literal: lit})
+		}
+		p.parts = append(p.parts, patternPart{name: name, catchAll: catchAll})
+		p.names = append(p.names, name)
+
+		lit = "/"
+		if i == len(segments)-1 {
+			lit = ""
+		}
+	}
+
+	if lit != "" {
+		p.parts = append(p.parts, patternPart{literal: lit})
+	}
+	return p
+}
+
+// match returns the captured values in the order of p.names, or ok false if
+// path does not match.
+func (p *pattern) match(path string) (values []string, ok bool) {
+	if len(p.names) > 0 {
+		values = make([]string, 0, len(p.names))
+	}
+
+	for _, part := range p.parts {
+		switch {
+		case part.name == "":
+			if !strings.HasPrefix(path, part.literal) {
+				return nil, false
+			}
+			path = path[len(part.literal):]
+		case part.catchAll:
+			values = append(values, path)
+			path = ""
+		default:
+			i := strings.IndexByte(path, '/')
+			if i < 0 {
+				i = len(path)
+			}
+			if i == 0 {
+				return nil, false
+			}
+			values = append(values, path[:i])
+			path = path[i:]
+		}
+	}
+
+	if path != "" {
+		return nil, false
+	}
+	return values, true
+}
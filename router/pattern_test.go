@@ -0,0 +1,99 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		ok            bool
+		exp           []string
+	}{
+		{"/", "/", true, nil},
+		{"/", "/a", false, nil},
+		{"/users", "/users", true, nil},
+		{"/users", "/users/", false, nil},
+		{"/users/:id", "/users/42", true, []string{"42"}},
+		{"/users/:id", "/users/", false, nil},
+		{"/users/:id", "/users/42/", false, nil},
+		{"/users/:id/", "/users/42/", true, []string{"42"}},
+		{"/users/:id/posts/:post", "/users/42/posts/7", true, []string{"42", "7"}},
+		{"/users/:id/posts/:post", "/users/42/posts", false, nil},
+		{"/users/:id/posts/:post", "/users/42/comments/7", false, nil},
+		{"/:a/:b", "/x/y", true, []string{"x", "y"}},
+		{"/files/*path", "/files/", true, []string{""}},
+		{"/files/*path", "/files/a/b.txt", true, []string{"a/b.txt"}},
+		{"/files/*path", "/files", false, nil},
+		{"/users/:id/*rest", "/users/1/a/b", true, []string{"1", "a/b"}},
+	}
+
+	for _, test := range tests {
+		p := compilePattern(test.pattern)
+		act, ok := p.match(test.path)
+		if ok != test.ok {
+			t.Errorf("%s %s: expected match %v", test.pattern, test.path, test.ok)
+			continue
+		}
+		if ok {
+			assertEqual(t, test.exp, act)
+		}
+	}
+}
+
+func TestPatternPanic(t *testing.T) {
+	assertPanic(t, `pattern "users/:id" must begin with /`, func() {
+		compilePattern("users/:id")
+	})
+	assertPanic(t, `pattern "/files/*path/edit": "*path" must be the last segment`, func() {
+		compilePattern("/files/*path/edit")
+	})
+	assertPanic(t, `pattern "/users/:id/:id": duplicate parameter "id"`, func() {
+		compilePattern("/users/:id/:id")
+	})
+	assertPanic(t, `pattern "/users/:/posts": parameter in segment 2 has no name`, func() {
+		compilePattern("/users/:/posts")
+	})
+	assertPanic(t, `pattern "/users/:id:name": invalid parameter name "id:name"`, func() {
+		compilePattern("/users/:id:name")
+	})
+	assertPanic(t, `pattern "/users/id:name": parameters must span a whole segment`, func() {
+		compilePattern("/users/id:name")
+	})
+}
+
+func TestLocationPattern(t *testing.T) {
+	r := NewRouter()
+
+	tc := testCapture{r}
+
+	r.Location(":", "/users/:id/posts/:post", tc)
+	r.Location(":", "/files/*path", tc)
+
+	assertMatch(t, r, "/users/42/posts/7", `[{id 42} {post 7}]`)
+	assertMatch(t, r, "/files/a/b.txt", `[{path a/b.txt}]`)
+
+	req, _ := http.NewRequest("GET", "/users/42/posts/7", nil)
+	assertEqual(t, "7", r.Params(req).ByName("post"))
+}
+
+func BenchmarkPatternMatch(b *testing.B) {
+	p := compilePattern("/users/:id/posts/:post")
+	for i := 0; i < b.N; i++ {
+		p.match("/users/42/posts/7")
+	}
+}
+
+func ExampleRouter_LocationPattern() {
+	r := NewRouter()
+
+	r.LocationPattern("/users/:id/posts/:post", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ps := ParamsFromContext(req.Context())
+		fmt.Println(ps.ByName("id"), ps.ByName("post"))
+	}))
+
+	serve(r, "/users/42/posts/7")
+	// Output: 42 7
+}
@@ -0,0 +1,297 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// maxRewrites limits how many times locations are searched again after a
+// rewrite, same as nginx.
+const maxRewrites = 10
+
+type rewriteRule struct {
+	regexp      *regexp.Regexp
+	replacement string
+	flag        string
+}
+
+var rewriteFlags = map[string]bool{
+	"": true, "last": true, "break": true, "redirect": true, "permanent": true,
+}
+
+func newRewriteRule(pattern, replacement, flag string) rewriteRule {
+	if !rewriteFlags[flag] {
+		panic(fmt.Sprintf("rewrite flag %q is not supported", flag))
+	}
+	return rewriteRule{regexp.MustCompile(pattern), replacement, flag}
+}
+
+// Rewrite adds a rule that is applied to every request before a location is
+// searched, like the nginx rewrite directive in a server block. Rules are
+// applied in the order they are added.
+//
+// If pattern matches the URL Path, the path is replaced by replacement, in
+// which $1, $name or ${name} refer to the groups of pattern. The variables
+// $uri, $request_uri, $args, $host and $scheme, and the Params of a parent
+// Router, are also available. Unless the replacement ends in "?", the original
+// query string is kept. A replacement starting with http://, https:// or
+// $scheme redirects with 302 Found.
+//
+// flag is one of
+//
+//	""        continue with the next rule
+//	last      stop applying rules
+//	break     stop applying rules
+//	redirect  redirect to the replacement with 302 Found
+//	permanent redirect to the replacement with 301 Moved Permanently
+//
+// Rewrite panics if pattern does not compile or flag is not supported.
+func (r *Router) Rewrite(pattern, replacement, flag string) {
+	rule := newRewriteRule(pattern, replacement, flag)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rewrites = append(r.rewrites, rule)
+}
+
+// Rewrite adds a rule that is applied when r is selected, like the nginx
+// rewrite directive in a location block. See Router.Rewrite for pattern and
+// replacement. flag is one of
+//
+//	""        continue with the next rule
+//	last      stop applying rules and search for a location with the new path
+//	break     stop applying rules and serve r with the new path
+//	redirect  redirect to the replacement with 302 Found
+//	permanent redirect to the replacement with 301 Moved Permanently
+//
+// If the rules change the path without last or break, a location is searched
+// for again. If that happens more than 10 times the request fails with 500
+// Internal Server Error.
+func (r *Route) Rewrite(pattern, replacement, flag string) *Route {
+	rule := newRewriteRule(pattern, replacement, flag)
+
+	r.router.mu.Lock()
+	defer r.router.mu.Unlock()
+
+	r.rewrites = append(r.rewrites, rule)
+	return r
+}
+
+// rewriteResult says what to do after applying rewrite rules.
+type rewriteResult int
+
+const (
+	rewriteNone     rewriteResult = iota // path is unchanged
+	rewriteSearch                        // search locations with the new path
+	rewriteBreak                         // serve the current location
+	rewriteRedirect                      // a redirect was written
+)
+
+// applyRewrites returns the rewritten request and what to do with it.
+func applyRewrites(w http.ResponseWriter, req *http.Request, rules []rewriteRule) (*http.Request, rewriteResult) {
+	result := rewriteNone
+
+	for _, rule := range rules {
+		m := rule.regexp.FindStringSubmatchIndex(req.URL.Path)
+		if m == nil {
+			continue
+		}
+
+		target := expand(rule.replacement, func(name string) (string, bool) {
+			if i := rule.regexp.SubexpIndex(name); i > 0 && m[2*i] >= 0 {
+				return req.URL.Path[m[2*i]:m[2*i+1]], true
+			}
+			if n, ok := groupNumber(name); ok && n <= rule.regexp.NumSubexp() {
+				if m[2*n] < 0 {
+					return "", true
+				}
+				return req.URL.Path[m[2*n]:m[2*n+1]], true
+			}
+			return lookupVar(req, name)
+		})
+
+		switch {
+		case rule.flag == "permanent":
+			http.Redirect(w, req, appendArgs(target, req), http.StatusMovedPermanently)
+			return req, rewriteRedirect
+		case rule.flag == "redirect" || isAbsolute(target):
+			http.Redirect(w, req, appendArgs(target, req), http.StatusFound)
+			return req, rewriteRedirect
+		}
+
+		req = rewriteURL(req, target)
+		result = rewriteSearch
+
+		switch rule.flag {
+		case "last":
+			return req, rewriteSearch
+		case "break":
+			return req, rewriteBreak
+		}
+	}
+	return req, result
+}
+
+// rewriteURL returns a shallow copy of req with the path (and query, if given)
+// of target.
+func rewriteURL(req *http.Request, target string) *http.Request {
+	u := *req.URL
+	path, query := target, ""
+	dropArgs := false
+	if i := strings.IndexByte(target, '?'); i >= 0 {
+		path, query = target[:i], target[i+1:]
+		dropArgs = query == ""
+	}
+
+	u.Path, u.RawPath = path, ""
+	switch {
+	case dropArgs:
+		u.RawQuery = ""
+	case query != "" && req.URL.RawQuery != "":
+		u.RawQuery = query + "&" + req.URL.RawQuery
+	case query != "":
+		u.RawQuery = query
+	}
+
+	r2 := new(http.Request)
+	*r2 = *req
+	r2.URL = &u
+	return r2
+}
+
+// appendArgs adds the original query string to a redirect target, unless the
+// target ends in "?".
+func appendArgs(target string, req *http.Request) string {
+	if strings.HasSuffix(target, "?") {
+		return target[:len(target)-1]
+	}
+	if req.URL.RawQuery == "" {
+		return target
+	}
+	if strings.IndexByte(target, '?') >= 0 {
+		return target + "&" + req.URL.RawQuery
+	}
+	return target + "?" + req.URL.RawQuery
+}
+
+func isAbsolute(target string) bool {
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
+
+var redirectCodes = map[int]bool{
+	http.StatusMovedPermanently:  true,
+	http.StatusFound:             true,
+	http.StatusSeeOther:          true,
+	http.StatusTemporaryRedirect: true,
+	http.StatusPermanentRedirect: true,
+}
+
+// Redirect returns a handler that redirects with code to target. target may
+// refer to the Params of the matched location ($id or ${id}) and to the
+// variables $uri, $request_uri, $args, $host and $scheme. Like nginx return,
+// the query string is not added to target.
+//
+// Redirect panics if code is not one of 301, 302, 303, 307 or 308.
+func Redirect(code int, target string) http.Handler {
+	if !redirectCodes[code] {
+		panic(fmt.Sprintf("redirect code %d is not supported", code))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, expand(target, func(name string) (string, bool) {
+			return lookupVar(req, name)
+		}), code)
+	})
+}
+
+// lookupVar returns nginx like variables and the Params of req.
+func lookupVar(req *http.Request, name string) (string, bool) {
+	switch name {
+	case "uri":
+		return req.URL.Path, true
+	case "request_uri":
+		if req.RequestURI != "" {
+			return req.RequestURI, true
+		}
+		return req.URL.RequestURI(), true
+	case "args":
+		return req.URL.RawQuery, true
+	case "host":
+		return stripPort(req.Host), true
+	case "scheme":
+		if req.TLS != nil {
+			return "https", true
+		}
+		return "http", true
+	}
+	return lookupParam(ParamsFromContext(req.Context()), name)
+}
+
+// expand replaces $name and ${name} in tmpl using lookup. Unknown names are
+// replaced with "". "$$" is a literal "$".
+func expand(tmpl string, lookup func(name string) (string, bool)) string {
+	if strings.IndexByte(tmpl, '$') < 0 {
+		return tmpl
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(tmpl, '$')
+		if i < 0 || i == len(tmpl)-1 {
+			b.WriteString(tmpl)
+			return b.String()
+		}
+		b.WriteString(tmpl[:i])
+		tmpl = tmpl[i+1:]
+
+		var name string
+		switch {
+		case tmpl[0] == '$':
+			b.WriteByte('$')
+			tmpl = tmpl[1:]
+			continue
+		case tmpl[0] == '{':
+			end := strings.IndexByte(tmpl, '}')
+			if end < 0 {
+				b.WriteString("${")
+				tmpl = tmpl[1:]
+				continue
+			}
+			name, tmpl = tmpl[1:end], tmpl[end+1:]
+		case tmpl[0] >= '0' && tmpl[0] <= '9':
+			// $1 is a single digit, like nginx.
+			name, tmpl = tmpl[:1], tmpl[1:]
+		default:
+			j := 0
+			for j < len(tmpl) && isNameByte(tmpl[j]) {
+				j++
+			}
+			if j == 0 {
+				b.WriteByte('$')
+				continue
+			}
+			name, tmpl = tmpl[:j], tmpl[j:]
+		}
+
+		v, _ := lookup(name)
+		b.WriteString(v)
+	}
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func groupNumber(name string) (int, bool) {
+	n := 0
+	for i := 0; i < len(name); i++ {
+		if name[i] < '0' || name[i] > '9' {
+			return 0, false
+		}
+		n = n*10 + int(name[i]-'0')
+	}
+	return n, len(name) > 0
+}
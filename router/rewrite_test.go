@@ -0,0 +1,140 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func showURL(w http.ResponseWriter, req *http.Request) {
+	fmt.Fprintf(w, "%s?%s %v", req.URL.Path, req.URL.RawQuery, ParamsFromContext(req.Context()))
+}
+
+func TestRewrite(t *testing.T) {
+	r := NewRouter()
+
+	r.Rewrite(`^/old/(.*)$`, "/new/$1", "last")
+	r.Rewrite(`^/blog/(?P<year>\d+)/(?P<slug>.*)$`, "/posts/${slug}?year=$year", "")
+	r.Rewrite(`^/drop-args$`, "/args?", "")
+	r.Rewrite(`^/moved/(.*)$`, "/new/$1", "permanent")
+	r.Rewrite(`^/elsewhere/(.*)$`, "https://example.com/$1", "")
+
+	r.LocationFunc("^~", "/new/", showURL)
+	r.LocationFunc(":", "/posts/:slug", showURL)
+	r.LocationFunc("=", "/args", showURL)
+
+	tests := []struct {
+		path     string
+		code     int
+		body     string
+		location string
+	}{
+		{"/old/a/b?x=1", 200, "/new/a/b?x=1 []", ""},
+		{"/blog/2017/hello?x=1", 200, "/posts/hello?year=2017&x=1 [{slug hello}]", ""},
+		{"/drop-args?x=1", 200, "/args? []", ""},
+		{"/moved/a?x=1", 301, "", "/new/a?x=1"},
+		{"/elsewhere/a", 302, "", "https://example.com/a"},
+	}
+
+	for _, test := range tests {
+		w := serve(r, test.path)
+		assertEqual(t, test.code, w.Code)
+		if test.body != "" {
+			assertEqual(t, test.body, w.Body.String())
+		}
+		assertEqual(t, test.location, w.Header().Get("Location"))
+	}
+}
+
+func TestRouteRewrite(t *testing.T) {
+	r := NewRouter()
+
+	r.LocationFunc("^~", "/download/", showURL).
+		Rewrite(`^(/download/.*)/media/(.*)\..*$`, "$1/mp3/$2.mp3", "").
+		Rewrite(`^(/download/.*)/audio/(.*)\..*$`, "$1/mp3/$2.ra", "break")
+	r.Location("^~", "/legacy/", nil).
+		Rewrite(`^/legacy/(?P<id>\d+)$`, "/items/$id", "last")
+	r.Location("^~", "/go/", nil).
+		Rewrite(`^/go/(.*)$`, "/items/$1", "redirect")
+	r.Location("^~", "/loop/", nil).
+		Rewrite(`^/loop/(.*)$`, "/loop/$1", "last")
+	r.LocationFunc(":", "/items/:id", showURL).
+		Rewrite(`^/items/(\d+)$`, "/items/$1.json", "break")
+
+	tests := []struct {
+		path     string
+		code     int
+		body     string
+		location string
+	}{
+		// no flag: the path changed, so locations are searched again.
+		{"/download/a/media/b.wav", 200, "/download/a/mp3/b.mp3? []", ""},
+		{"/download/a/audio/b.wav", 200, "/download/a/mp3/b.ra? []", ""},
+		{"/legacy/42", 200, "/items/42.json? [{id 42.json}]", ""},
+		{"/go/42", 302, "", "/items/42"},
+		{"/loop/a", 500, "500 rewrite cycle\n", ""},
+	}
+
+	for _, test := range tests {
+		w := serve(r, test.path)
+		assertEqual(t, test.code, w.Code)
+		if test.body != "" {
+			assertEqual(t, test.body, w.Body.String())
+		}
+		assertEqual(t, test.location, w.Header().Get("Location"))
+	}
+
+	assertPanic(t, `rewrite flag "stop" is not supported`, func() {
+		r.Rewrite("/", "/", "stop")
+	})
+}
+
+func TestRedirect(t *testing.T) {
+	r := NewRouter()
+
+	r.Location(":", "/users/:id", Redirect(http.StatusPermanentRedirect, "$scheme://new.$host/people/${id}?from=$uri"))
+	r.Location("=", "/old", Redirect(http.StatusSeeOther, "/new$$"))
+
+	req := httptest.NewRequest("POST", "http://example.com:8080/users/42?x=1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assertEqual(t, 308, w.Code)
+	assertEqual(t, "http://new.example.com/people/42?from=/users/42", w.Header().Get("Location"))
+
+	w = serve(r, "/old")
+	assertEqual(t, 303, w.Code)
+	assertEqual(t, "/new$", w.Header().Get("Location"))
+
+	assertPanic(t, "redirect code 200 is not supported", func() {
+		Redirect(200, "/")
+	})
+}
+
+func TestExpand(t *testing.T) {
+	vars := map[string]string{"a": "A", "ab": "AB", "1": "one"}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+
+	tests := []struct {
+		tmpl, exp string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{"$a", "A"},
+		{"$ab", "AB"},
+		{"${a}b", "Ab"},
+		{"$12", "one2"},
+		{"$missing!", "!"},
+		{"$$a", "$a"},
+		{"a$", "a$"},
+		{"$-", "$-"},
+		{"${a", "${a"},
+	}
+
+	for _, test := range tests {
+		assertEqual(t, test.exp, expand(test.tmpl, lookup))
+	}
+}
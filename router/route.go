@@ -0,0 +1,185 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/bhenderson/web"
+)
+
+// Route is a handler registered at a location. By default a Route handles
+// every request that reaches its location; Methods and Host narrow that down.
+//
+// If a location matches the request path but none of its routes match the
+// method, the Router replies with 405 Method Not Allowed and an Allow header
+// (see web.MethodNotAllowed). OPTIONS requests get the Allow header only. Like
+// web.Method, the Allow header always lists OPTIONS.
+//
+// Produces and Consumes select between routes by media type. If routes match
+// the method but none accepts the Content-Type of the request the Router
+// replies with 415 Unsupported Media Type, and if none produces a type the
+// client accepts, with 406 Not Acceptable.
+type Route struct {
+	router   *Router
+	location *locationHandler
+	handler  http.Handler
+
+	name string
+
+	methods []string
+	host    string
+
+	produces []string
+	consumes []string
+
+	rewrites []rewriteRule
+}
+
+// Methods restricts r to the given HTTP methods.
+func (r *Route) Methods(methods ...string) *Route {
+	r.router.mu.Lock()
+	defer r.router.mu.Unlock()
+
+	for _, m := range methods {
+		r.methods = appendMethods(r.methods, strings.ToUpper(m))
+	}
+	return r
+}
+
+// Host restricts r to requests for host. The port of the Host header is
+// ignored and the comparison is case-insensitive. Routes that don't match the
+// host are skipped entirely, so they don't contribute to the Allow header.
+func (r *Route) Host(host string) *Route {
+	r.router.mu.Lock()
+	defer r.router.mu.Unlock()
+
+	r.host = strings.ToLower(host)
+	return r
+}
+
+// Produces declares the media types r responds with. Among the routes of a
+// location that match the request, the one producing the type that best
+// matches the Accept header is used (see web.NegotiateContentType); routes
+// that don't declare any types are used only if none of the others is
+// acceptable. The Content-Type header is set to the selected type unless the
+// handler sets it.
+func (r *Route) Produces(types ...string) *Route {
+	r.router.mu.Lock()
+	defer r.router.mu.Unlock()
+
+	r.produces = append(r.produces, types...)
+	return r
+}
+
+// Consumes restricts r to requests whose Content-Type matches one of types,
+// which may contain wildcards such as text/*. See web.MatchContentType.
+func (r *Route) Consumes(types ...string) *Route {
+	r.router.mu.Lock()
+	defer r.router.mu.Unlock()
+
+	r.consumes = append(r.consumes, types...)
+	return r
+}
+
+// Name registers r under name for Router.URL. Name panics if name is already
+// taken.
+func (r *Route) Name(name string) *Route {
+	r.router.mu.Lock()
+	defer r.router.mu.Unlock()
+
+	if _, ok := r.router.names[name]; ok {
+		panic(fmt.Sprintf("route name %q is already registered", name))
+	}
+	if r.router.names == nil {
+		r.router.names = make(map[string]*Route)
+	}
+	r.router.names[name] = r
+	r.name = name
+	return r
+}
+
+func (r *Router) hasName(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.names[name]
+	return ok
+}
+
+func (r *Route) matchMethod(req *http.Request) bool {
+	if len(r.methods) == 0 {
+		return true
+	}
+	for _, m := range r.methods {
+		if m == req.Method {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Route) matchHost(req *http.Request) bool {
+	if r.host == "" {
+		return true
+	}
+	return strings.ToLower(stripPort(req.Host)) == r.host
+}
+
+func (r *Route) matchContentType(req *http.Request) bool {
+	return len(r.consumes) == 0 || web.MatchContentType(req, r.consumes...) != ""
+}
+
+// negotiate returns the route producing the type that best matches the Accept
+// header of req, and that type. Routes without Produces are a fallback. rt is
+// nil if nothing is acceptable. negotiated is set if any route declares
+// Produces.
+func negotiate(req *http.Request, routes []*Route) (rt *Route, typ string, negotiated bool) {
+	var offers []string
+	var owners []*Route
+	for _, r := range routes {
+		for _, t := range r.produces {
+			offers = append(offers, t)
+			owners = append(owners, r)
+		}
+	}
+
+	if len(offers) > 0 {
+		if t := web.NegotiateContentType(req, offers...); t != "" {
+			for i, o := range offers {
+				if o == t {
+					return owners[i], t, true
+				}
+			}
+		}
+	}
+
+	for _, r := range routes {
+		if len(r.produces) == 0 {
+			return r, "", len(offers) > 0
+		}
+	}
+	return nil, "", len(offers) > 0
+}
+
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// appendMethods appends methods to a, skipping duplicates.
+func appendMethods(a []string, methods ...string) []string {
+outer:
+	for _, m := range methods {
+		for _, o := range a {
+			if o == m {
+				continue outer
+			}
+		}
+		a = append(a, m)
+	}
+	return a
+}
@@ -0,0 +1,149 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// bodyHandler writes the string as the response body.
+type bodyHandler string
+
+func (b bodyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, b)
+}
+
+func TestRouteMethods(t *testing.T) {
+	r := NewRouter()
+
+	r.Location("=", "/users", bodyHandler("list")).Methods("GET")
+	r.Location("=", "/users", bodyHandler("create")).Methods("post")
+	r.Location(":", "/users/:id", bodyHandler("show")).Methods("GET", "HEAD")
+	r.Location(":", "/users/:id", bodyHandler("delete")).Methods("DELETE")
+	r.Location("", "/", bodyHandler("any"))
+
+	tests := []struct {
+		method, path string
+		code         int
+		body, allow  string
+	}{
+		{"GET", "/users", 200, "list", ""},
+		{"POST", "/users", 200, "create", ""},
+		{"PUT", "/users", 405, "405 method not allowed\n", "GET, OPTIONS, POST"},
+		{"OPTIONS", "/users", 200, "", "GET, OPTIONS, POST"},
+		{"HEAD", "/users/1", 200, "show", ""},
+		{"DELETE", "/users/1", 200, "delete", ""},
+		{"PATCH", "/users/1", 405, "405 method not allowed\n", "DELETE, GET, HEAD, OPTIONS"},
+		{"PATCH", "/other", 200, "any", ""},
+	}
+
+	for _, test := range tests {
+		w := serveMethod(r, test.method, test.path)
+		if w.Code != test.code {
+			t.Errorf("%s %s: expected %d got %d", test.method, test.path, test.code, w.Code)
+		}
+		assertEqual(t, test.body, w.Body.String())
+		assertEqual(t, test.allow, w.Header().Get("Allow"))
+	}
+}
+
+func TestRouteHost(t *testing.T) {
+	r := NewRouter()
+
+	r.Location("", "/", bodyHandler("admin")).Host("Admin.example.com")
+	r.Location("", "/", bodyHandler("api")).Host("api.example.com").Methods("GET")
+
+	tests := []struct {
+		method, host string
+		exp          string
+		code         int
+	}{
+		{"GET", "admin.example.com", "admin", 200},
+		{"POST", "admin.example.com:8080", "admin", 200},
+		{"GET", "api.example.com", "api", 200},
+		{"POST", "api.example.com", "", 405},
+		{"GET", "example.com", "", 404},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest(test.method, "/", nil)
+		req.Host = test.host
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assertEqual(t, test.code, w.Code)
+		if test.code == 200 {
+			assertEqual(t, test.exp, w.Body.String())
+		}
+	}
+}
+
+func serveHeader(r http.Handler, method, path, header, value string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	if value != "" {
+		req.Header.Set(header, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRouteProduces(t *testing.T) {
+	r := NewRouter()
+	r.Location("=", "/users", bodyHandler("html")).Produces("text/html")
+	r.Location("=", "/users", bodyHandler("json")).Produces("application/json")
+	r.Location("=", "/only-json", bodyHandler("json")).Produces("application/json")
+	r.Location("=", "/fallback", bodyHandler("csv")).Produces("text/csv")
+	r.Location("=", "/fallback", bodyHandler("any"))
+
+	tests := []struct {
+		path, accept string
+		code         int
+		exp, typ     string
+	}{
+		{"/users", "", 200, "html", "text/html"},
+		{"/users", "application/json", 200, "json", "application/json"},
+		{"/users", "text/*;q=0.5, application/*;q=0.6", 200, "json", "application/json"},
+		{"/only-json", "text/html", 406, "406 not acceptable\n", "text/plain; charset=utf-8"},
+		{"/fallback", "text/csv", 200, "csv", "text/csv"},
+		{"/fallback", "image/png", 200, "any", "text/plain; charset=utf-8"},
+	}
+
+	for _, test := range tests {
+		w := serveHeader(r, "GET", test.path, "Accept", test.accept)
+		assertEqual(t, test.code, w.Code)
+		assertEqual(t, test.exp, w.Body.String())
+		assertEqual(t, test.typ, w.Header().Get("Content-Type"))
+		assertEqual(t, "Accept", w.Header().Get("Vary"))
+	}
+}
+
+func TestRouteConsumes(t *testing.T) {
+	r := NewRouter()
+	r.Location("=", "/users", bodyHandler("list")).Methods("GET")
+	r.Location("=", "/users", bodyHandler("json")).Methods("POST").Consumes("application/json")
+	r.Location("=", "/users", bodyHandler("form")).Methods("POST").Consumes("multipart/*", "application/x-www-form-urlencoded")
+
+	tests := []struct {
+		method, contentType string
+		code                int
+		exp                 string
+	}{
+		{"GET", "", 200, "list"},
+		{"POST", "application/json; charset=utf-8", 200, "json"},
+		{"POST", "multipart/form-data; boundary=x", 200, "form"},
+		{"POST", "text/xml", 415, "415 unsupported media type\n"},
+		{"POST", "", 415, "415 unsupported media type\n"},
+		{"PUT", "application/json", 405, "405 method not allowed\n"},
+	}
+
+	for _, test := range tests {
+		w := serveHeader(r, test.method, "/users", "Content-Type", test.contentType)
+		assertEqual(t, test.code, w.Code)
+		assertEqual(t, test.exp, w.Body.String())
+	}
+
+	e := r.Explain(httptest.NewRequest("POST", "/users", nil))
+	assertEqual(t, http.StatusUnsupportedMediaType, e.Status)
+}
@@ -4,76 +4,217 @@
 package router
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"regexp"
 	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bhenderson/web"
 )
 
 func NewRouter() *Router {
-	return &Router{
-		requests: make(map[*http.Request]*locationHandler),
-		exact:    make(map[string]locationHandler),
-	}
+	return &Router{}
 }
 
+// Router is safe for concurrent use. Locations may be added while requests
+// are being served.
 type Router struct {
 	// NotFound handles the case when no location matches. Defaults to
 	// http.NotFound
 	NotFound http.HandlerFunc
 
-	exact     map[string]locationHandler
-	locations []locationHandler
-	regexps   []locationHandler
+	// PathPolicy is applied to the request path before anything else. See
+	// web.PathPolicy. Redirects of a mounted Router keep the prefix of the
+	// mount, see MountPrefix.
+	PathPolicy web.PathPolicy
+
+	mu sync.RWMutex
+
+	// exact and prefix locations
+	tree    node
+	regexps []*locationHandler
 
-	requests map[*http.Request]*locationHandler
+	// named routes, see Route.Name
+	names map[string]*Route
+
+	// server level rewrites, see Router.Rewrite
+	rewrites []rewriteRule
+
+	// mount prefixes without the trailing slash, see Mount
+	slashes map[string]*locationHandler
 }
 
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	defer func() {
-		// cleanup r.requests after processing.
-		delete(r.requests, req)
-	}()
-
-	h := r.match(req)
-	if h != nil {
-		h.ServeHTTP(w, req)
-	} else if r.NotFound != nil {
+	if req = r.PathPolicy.ApplyPrefix(w, req, MountPrefix(req)); req == nil {
+		return
+	}
+
+	req, result := applyRewrites(w, req, r.rules(nil))
+	if result == rewriteRedirect {
+		return
+	}
+
+	var h *locationHandler
+	var m routeMatch
+
+	for i := 0; ; i++ {
+		h, m = r.handler(req, nil)
+		if m.route == nil {
+			break
+		}
+
+		req, result = applyRewrites(w, req, r.rules(m.route))
+		if result == rewriteRedirect {
+			return
+		}
+		if result != rewriteSearch {
+			break
+		}
+		if i == maxRewrites {
+			http.Error(w, "500 rewrite cycle", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if h == nil {
+		r.notFound(w, req)
+		return
+	}
+
+	if m.negotiated {
+		w.Header().Add("Vary", "Accept")
+	}
+
+	switch {
+	case m.route != nil:
+	case m.status == http.StatusMethodNotAllowed && req.Method == "OPTIONS":
+		// The default OPTIONS response, same as web.Method.
+		w.Header().Set("Allow", strings.Join(m.allowed, ", "))
+		return
+	case m.status == http.StatusMethodNotAllowed:
+		web.MethodNotAllowed(w, req, m.allowed...)
+		return
+	case m.status == http.StatusUnsupportedMediaType:
+		web.UnsupportedMediaType(w, req)
+		return
+	case m.status == http.StatusNotAcceptable:
+		web.NotAcceptable(w, req)
+		return
+	default:
+		r.notFound(w, req)
+		return
+	}
+
+	if m.typ != "" && !strings.Contains(m.typ, "*") && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", m.typ)
+	}
+
+	// break keeps the location, but its params come from the new path.
+	if result == rewriteBreak {
+		if l := h.match(req); l != nil {
+			h = l
+		}
+	}
+
+	// params travel with the request so that handlers (and any go
+	// routines they start) can read them without touching the Router.
+	if ps := h.params(); len(ps) > 0 {
+		req = req.WithContext(withParams(req.Context(), ps))
+	}
+	m.route.handler.ServeHTTP(w, req)
+}
+
+// rules returns the rewrite rules of rt, or of r if rt is nil.
+func (r *Router) rules(rt *Route) []rewriteRule {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if rt == nil {
+		return r.rewrites
+	}
+	return rt.rewrites
+}
+
+func (r *Router) notFound(w http.ResponseWriter, req *http.Request) {
+	if r.NotFound != nil {
 		r.NotFound(w, req)
 	} else {
 		http.NotFound(w, req)
 	}
 }
 
-func (r *Router) match(req *http.Request) (lp *locationHandler) {
-	// Check priority, but maintain order. This lets applications configure
-	// "popular" routes.
+// handler returns the location and route matching req. explain, if not nil,
+// records the locations considered.
+func (r *Router) handler(req *http.Request, explain explainFunc) (h *locationHandler, m routeMatch) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if h = r.location(req, explain); h != nil {
+		m = h.route(req)
+	}
+	return
+}
+
+func (r *Router) match(req *http.Request) *locationHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
-	// check exacts
-	// check prefixes
-	// check regexps
-	// check locations
+	return r.location(req, nil)
+}
+
+// explainFunc records a location considered by Router.location, see
+// Router.Explain.
+type explainFunc func(l *locationHandler, matched bool, reason string)
 
-	// Seems messy. should I pass path to all match?
-	if l, ok := r.exact[req.URL.Path]; ok {
-		return &l
+func (f explainFunc) add(l *locationHandler, matched bool, reason string) {
+	if f != nil {
+		f(l, matched, reason)
 	}
+}
 
-	// locations are now sorted by longest path, so we use the first matched.
-	for _, nl := range r.locations {
-		if lp = nl.match(req); lp != nil {
-			if lp.noRegexs {
-				return lp
+// location must be called with r.mu held.
+func (r *Router) location(req *http.Request, explain explainFunc) *locationHandler {
+	// nginx precedence:
+	//   exact match
+	//   longest prefix, if it is ^~
+	//   regexps in declaration order
+	//   longest prefix
+	exact, lp := r.tree.lookup(req.URL.Path)
+	if exact != nil {
+		explain.add(exact, true, "exact match, stop searching")
+		return exact
+	}
+	if l := r.slashes[req.URL.Path]; l != nil {
+		explain.add(l, true, "mount without trailing slash, redirect")
+		return l
+	}
+
+	if lp != nil {
+		if lp.noRegexs {
+			explain.add(lp, true, "longest prefix match, ^~ stops searching")
+		} else {
+			explain.add(lp, true, "longest prefix match, remembered while checking regexps")
+		}
+		if explain != nil {
+			ps := r.tree.prefixes(req.URL.Path)
+			for i := len(ps) - 2; i >= 0; i-- {
+				explain(ps[i], false, "prefix match, not the longest")
 			}
-			break
+		}
+		if lp.noRegexs {
+			return lp
 		}
 	}
 
 	for _, nl := range r.regexps {
 		if l := nl.match(req); l != nil {
-			r.requests[req] = l
+			explain.add(nl, true, "first regexp match, stop searching")
 			return l
 		}
+		explain.add(nl, false, "no match")
 	}
 
 	// return matched location if no regexps
@@ -85,75 +226,123 @@ func (r *Router) match(req *http.Request) (lp *locationHandler) {
 	=  LocationExact
 	~  LocationRegexp
 	~* LocationRegexp (?i)
+	:  LocationPattern (named segments, checked with regexps)
 	^~ LocationPrefix (stop regexp matching)
 	"" Location (prefix matching)
+
+Registering the same location more than once adds another Route to it. The
+returned Route can be constrained by method or host; the first Route that
+matches the request handles it.
 */
-func (r *Router) Location(kind, path string, h http.Handler) {
+func (r *Router) Location(kind, path string, h http.Handler) *Route {
 	switch kind {
 	case "=":
-		r.LocationExact(path, h)
+		return r.LocationExact(path, h)
 	case "~":
 		re := regexp.MustCompile(path)
-		r.LocationRegexp(re, h)
+		return r.LocationRegexp(re, h)
 	case "~*": // case insensitive
 		re := regexp.MustCompile("(?i)" + path)
-		r.LocationRegexp(re, h)
+		return r.LocationRegexp(re, h)
+	case ":":
+		return r.LocationPattern(path, h)
 	case "^~":
-		r.LocationPrefix(path, h)
+		return r.LocationPrefix(path, h)
 	case "":
-		r.addLocation(locationHandler{
-			location: path,
-			handler:  h,
-		})
+		return r.addLocation(path, false, h)
 	default:
 		panic(fmt.Sprintf("%q is not supported", kind))
 	}
 }
 
-func (r *Router) LocationFunc(kind, path string, h http.HandlerFunc) {
-	r.Location(kind, path, h)
+func (r *Router) LocationFunc(kind, path string, h http.HandlerFunc) *Route {
+	return r.Location(kind, path, h)
 }
 
 // LocationExact matches the URL Path exactly. Route processing immediately
 // stops.
 //
 // Same as Location("=", path, h)
-func (r *Router) LocationExact(path string, h http.Handler) {
-	r.exact[path] = locationHandler{
-		exact:   true,
-		handler: h,
+func (r *Router) LocationExact(path string, h http.Handler) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.tree.insert(path)
+	if n.exact == nil {
+		n.exact = &locationHandler{
+			location: path,
+			exact:    true,
+		}
 	}
+	return n.exact.addRoute(r, h)
 }
 
 // LocationPrefix matches the beginning of the url path and skips regexps after longest match.
 //
 // Same as Location("^~", path, h)
-func (r *Router) LocationPrefix(path string, h http.Handler) {
-	r.addLocation(locationHandler{
-		location: path,
-		noRegexs: true,
-		handler:  h,
-	})
+func (r *Router) LocationPrefix(path string, h http.Handler) *Route {
+	return r.addLocation(path, true, h)
 }
 
 // LocationRegexp matches the URL Path against the regexp.
 //
 // Same as Location("~", path, h)
 // To get case-insensitive matching, compile your regexp with (?i).
-func (r *Router) LocationRegexp(path *regexp.Regexp, h http.Handler) {
-	r.regexps = append(r.regexps, locationHandler{
+func (r *Router) LocationRegexp(path *regexp.Regexp, h http.Handler) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, l := range r.regexps {
+		if l.regexp != nil && l.regexp.String() == path.String() {
+			return l.addRoute(r, h)
+		}
+	}
+
+	l := &locationHandler{
 		regexp:   path,
 		capNames: path.SubexpNames()[1:],
-		handler:  h,
-	})
+	}
+	r.regexps = append(r.regexps, l)
+	return l.addRoute(r, h)
 }
 
-// Params returns the capture groups of a regexp location. If the cached
-// version is not found, request processing is re-run to find the params. This
-// is only the case if called outside r.ServeHTTP (like in a go routine).
+// LocationPattern matches the URL Path against a pattern of named segments.
+//
+//	/users/:id/posts/:post
+//	/files/*path
+//
+// :name matches one non-empty path segment, *name matches the rest of the path
+// and must be the last segment. The values are available through Params under
+// their names. Patterns are checked in declaration order along with regexps.
+// LocationPattern panics if path is not a valid pattern.
+//
+// Same as Location(":", path, h)
+func (r *Router) LocationPattern(path string, h http.Handler) *Route {
+	p := compilePattern(path)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, l := range r.regexps {
+		if l.pattern != nil && l.pattern.path == path {
+			return l.addRoute(r, h)
+		}
+	}
+
+	l := &locationHandler{
+		pattern:  p,
+		capNames: p.names,
+	}
+	r.regexps = append(r.regexps, l)
+	return l.addRoute(r, h)
+}
+
+// Params returns the capture groups of a regexp or pattern location. Params are read
+// from the request context when req was dispatched by r.ServeHTTP (see
+// ParamsFromContext), otherwise location matching is re-run to find them.
 func (r *Router) Params(req *http.Request) Params {
-	if l, ok := r.requests[req]; ok {
-		return l.params()
+	if ps, ok := req.Context().Value(paramsKey).(Params); ok {
+		return ps
 	}
 
 	if l := r.match(req); l != nil {
@@ -162,17 +351,20 @@ func (r *Router) Params(req *http.Request) Params {
 	return nil
 }
 
-func (r *Router) addLocation(h locationHandler) {
-	r.locations = append(r.locations, h)
-	sort.Stable(locationHandlers(r.locations))
-}
-
-// sort by longest location
-type locationHandlers []locationHandler
+func (r *Router) addLocation(path string, noRegexs bool, h http.Handler) *Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-func (s locationHandlers) Len() int           { return len(s) }
-func (s locationHandlers) Less(i, j int) bool { return -len(s[i].location) < -len(s[j].location) }
-func (s locationHandlers) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+	// the first location registered for a path decides its kind.
+	n := r.tree.insert(path)
+	if n.prefix == nil {
+		n.prefix = &locationHandler{
+			location: path,
+			noRegexs: noRegexs,
+		}
+	}
+	return n.prefix.addRoute(r, h)
+}
 
 type locationHandler struct {
 	location string
@@ -180,29 +372,88 @@ type locationHandler struct {
 	noRegexs bool
 
 	regexp               *regexp.Regexp
+	pattern              *pattern
 	capNames, capResults []string
 
-	handler http.Handler
+	routes []*Route
 }
 
-func (h locationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	h.handler.ServeHTTP(w, r)
+func (h *locationHandler) addRoute(r *Router, handler http.Handler) *Route {
+	rt := &Route{router: r, location: h, handler: handler}
+	h.routes = append(h.routes, rt)
+	return rt
 }
 
-// match needs to return a pointer in the case of modifying capResults
-// takes care to return a copy
-func (h locationHandler) match(r *http.Request) *locationHandler {
-	if h.exact {
-		return &h
+// routeMatch is the result of locationHandler.route.
+type routeMatch struct {
+	route *Route
+	// typ is the media type route was selected for, see Route.Produces.
+	typ string
+	// negotiated is set if the Accept header decided between routes.
+	negotiated bool
+
+	// if route is nil, status is http.StatusMethodNotAllowed (with the
+	// methods that would match in allowed), http.StatusUnsupportedMediaType,
+	// http.StatusNotAcceptable, or 0 if no route matches the host.
+	status  int
+	allowed []string
+}
+
+// route returns the first Route matching req, see routeMatch.
+func (h *locationHandler) route(req *http.Request) (m routeMatch) {
+	var routes []*Route
+	for _, rt := range h.routes {
+		if !rt.matchHost(req) {
+			continue
+		}
+		if rt.matchMethod(req) {
+			routes = append(routes, rt)
+			continue
+		}
+		m.allowed = appendMethods(m.allowed, rt.methods...)
+	}
+
+	if len(routes) == 0 {
+		if len(m.allowed) > 0 {
+			// OPTIONS always has a default response, see ServeHTTP.
+			m.allowed = appendMethods(m.allowed, "OPTIONS")
+			sort.Strings(m.allowed)
+			m.status = http.StatusMethodNotAllowed
+		}
+		return m
+	}
+	m.allowed = nil
+
+	var consumed []*Route
+	for _, rt := range routes {
+		if rt.matchContentType(req) {
+			consumed = append(consumed, rt)
+		}
+	}
+	if len(consumed) == 0 {
+		m.status = http.StatusUnsupportedMediaType
+		return m
 	}
 
+	m.route, m.typ, m.negotiated = negotiate(req, consumed)
+	if m.route == nil {
+		m.status = http.StatusNotAcceptable
+	}
+	return m
+}
+
+// match is used for regexp and pattern locations. It needs to return a
+// pointer in the case of modifying capResults; takes care to return a copy.
+func (h locationHandler) match(r *http.Request) *locationHandler {
 	path := r.URL.Path
 
-	if h.regexp == nil {
-		if len(path) >= len(h.location) && path[:len(h.location)] == h.location {
+	switch {
+	case h.pattern != nil:
+		if res, ok := h.pattern.match(path); ok {
+			h.capResults = res
 			return &h
 		}
-	} else {
+	case h.regexp != nil:
 		if res := h.regexp.FindStringSubmatch(path); len(res) > 0 {
 			h.capResults = res[1:]
 			return &h
@@ -221,6 +472,30 @@ func (h locationHandler) params() (p Params) {
 
 type Params []Param
 
+type contextKey int
+
+const (
+	paramsKey contextKey = iota
+	mountKey
+)
+
+// ParamsFromContext returns the Params stored in ctx by Router.ServeHTTP, or
+// nil if there are none. Params of mounted routers follow those of their
+// parents. It is safe to call from any go routine.
+func ParamsFromContext(ctx context.Context) Params {
+	ps, _ := ctx.Value(paramsKey).(Params)
+	return ps
+}
+
+// withParams adds ps after any params already in ctx, such as those of a
+// parent Router.
+func withParams(ctx context.Context, ps Params) context.Context {
+	if parent := ParamsFromContext(ctx); len(parent) > 0 {
+		ps = append(append(Params{}, parent...), ps...)
+	}
+	return context.WithValue(ctx, paramsKey, ps)
+}
+
 type Param struct {
 	Key   string
 	Value string
@@ -5,7 +5,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync"
 	"testing"
+
+	"github.com/bhenderson/web"
 )
 
 var config string
@@ -43,7 +46,11 @@ func ExampleRouter_Location() {
 }
 
 func serve(r *Router, path string) *httptest.ResponseRecorder {
-	req, _ := http.NewRequest("GET", path, nil)
+	return serveMethod(r, "GET", path)
+}
+
+func serveMethod(r *Router, method, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 	return w
@@ -74,6 +81,55 @@ func TestCaptures(t *testing.T) {
 	assertEqual(t, exp, act)
 }
 
+func TestParamsFromContext(t *testing.T) {
+	r := NewRouter()
+
+	done := make(chan Params)
+	r.LocationFunc("~", `^/users/(?P<id>\d+)$`, func(w http.ResponseWriter, req *http.Request) {
+		// params must survive the handler returning.
+		go func() {
+			done <- ParamsFromContext(req.Context())
+		}()
+	})
+
+	serve(r, "/users/42")
+	assertEqual(t, Params{{"id", "42"}}, <-done)
+
+	req, _ := http.NewRequest("GET", "/users/42", nil)
+	assertEqual(t, Params(nil), ParamsFromContext(req.Context()))
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	r := NewRouter()
+
+	r.LocationFunc("~", `^/users/(?P<id>\d+)$`, func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, r.Params(req).ByName("id"))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprint(i)
+			if body := serve(r, "/users/"+id).Body.String(); body != id {
+				t.Errorf("expected %q got %q", id, body)
+			}
+		}(i)
+	}
+
+	// registering locations while serving must not race.
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r.Location("", fmt.Sprintf("/static/%d/", i), testHandler("static"))
+			r.Location("=", fmt.Sprintf("/exact/%d", i), testHandler("exact"))
+		}(i)
+	}
+	wg.Wait()
+}
+
 func TestNotFound(t *testing.T) {
 	r := NewRouter()
 	r.NotFound = func(w http.ResponseWriter, r *http.Request) {
@@ -125,3 +181,34 @@ func assertEqual(t *testing.T, exp, act interface{}) {
 		)
 	}
 }
+
+func TestPathPolicy(t *testing.T) {
+	r := NewRouter()
+	r.Location("=", "/a/b", bodyHandler("ab"))
+	r.Location("^~", "/static/", bodyHandler("static"))
+
+	w := serve(r, "/a//b")
+	assertEqual(t, 404, w.Code)
+
+	r.PathPolicy = web.PathClean
+	w = serve(r, "/a//b")
+	assertEqual(t, "ab", w.Body.String())
+	w = serve(r, "/static/../a/./b")
+	assertEqual(t, "ab", w.Body.String())
+
+	r.PathPolicy = web.PathRedirect
+	w = serve(r, "/static//x.css?v=1")
+	assertEqual(t, 301, w.Code)
+	assertEqual(t, "/static/x.css?v=1", w.Header().Get("Location"))
+	w = serveMethod(r, "POST", "/a//b")
+	assertEqual(t, 308, w.Code)
+
+	r.PathPolicy = web.PathClean | web.PathStripSlash
+	w = serve(r, "/a/b/")
+	assertEqual(t, "ab", w.Body.String())
+
+	r.PathPolicy = web.PathRedirect | web.PathStripSlash
+	w = serve(r, "/a/b/")
+	assertEqual(t, 301, w.Code)
+	assertEqual(t, "/a/b", w.Header().Get("Location"))
+}
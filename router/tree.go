@@ -0,0 +1,132 @@
+package router
+
+import "sort"
+
+// node is a compressed radix tree (each edge holds a string rather than a
+// single byte) keyed by location path. A node may hold both an exact and a
+// prefix location for the same path.
+//
+// lookup walks the tree once, so finding the exact location and the longest
+// prefix location is O(len(path)) regardless of how many locations there are.
+type node struct {
+	path string
+
+	// indices holds the first byte of each child's path, in the same order
+	// as children.
+	indices  string
+	children []*node
+
+	exact, prefix *locationHandler
+}
+
+// insert returns the node for path, creating it if necessary.
+func (n *node) insert(path string) *node {
+	for {
+		// length of the common prefix
+		i := 0
+		max := len(path)
+		if len(n.path) < max {
+			max = len(n.path)
+		}
+		for i < max && path[i] == n.path[i] {
+			i++
+		}
+
+		// split the edge
+		if i < len(n.path) {
+			child := &node{
+				path:     n.path[i:],
+				indices:  n.indices,
+				children: n.children,
+				exact:    n.exact,
+				prefix:   n.prefix,
+			}
+			n.path = n.path[:i]
+			n.indices = string(child.path[0])
+			n.children = []*node{child}
+			n.exact, n.prefix = nil, nil
+		}
+
+		path = path[i:]
+		if len(path) == 0 {
+			return n
+		}
+
+		if next := n.child(path[0]); next != nil {
+			n = next
+			continue
+		}
+
+		child := &node{path: path}
+		n.indices += string(path[0])
+		n.children = append(n.children, child)
+		return child
+	}
+}
+
+func (n *node) child(c byte) *node {
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] == c {
+			return n.children[i]
+		}
+	}
+	return nil
+}
+
+// lookup returns the exact location for path and the longest prefix location
+// that matches path. Either may be nil.
+func (n *node) lookup(path string) (exact, prefix *locationHandler) {
+	for n != nil {
+		if len(path) < len(n.path) || path[:len(n.path)] != n.path {
+			return
+		}
+		path = path[len(n.path):]
+
+		if n.prefix != nil {
+			prefix = n.prefix
+		}
+		if len(path) == 0 {
+			exact = n.exact
+			return
+		}
+		n = n.child(path[0])
+	}
+	return
+}
+
+// walk calls f for every node holding a location, in lexical order of the
+// full paths.
+func (n *node) walk(prefix string, f func(path string, n *node)) {
+	prefix += n.path
+	if n.exact != nil || n.prefix != nil {
+		f(prefix, n)
+	}
+
+	children := make([]*node, len(n.children))
+	copy(children, n.children)
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].path < children[j].path
+	})
+	for _, c := range children {
+		c.walk(prefix, f)
+	}
+}
+
+// prefixes returns every prefix location matching path, shortest first.
+func (n *node) prefixes(path string) (ps []*locationHandler) {
+	for n != nil {
+		if len(path) < len(n.path) || path[:len(n.path)] != n.path {
+			return
+		}
+		path = path[len(n.path):]
+
+		if n.prefix != nil {
+			ps = append(ps, n.prefix)
+		}
+		if len(path) == 0 {
+			return
+		}
+		n = n.child(path[0])
+	}
+	return
+}
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+func TestTreeLookup(t *testing.T) {
+	var root node
+
+	add := func(path string, exact bool) {
+		n := root.insert(path)
+		l := &locationHandler{location: path, exact: exact}
+		if exact {
+			n.exact = l
+		} else {
+			n.prefix = l
+		}
+	}
+
+	add("/", false)
+	add("/", true)
+	add("/documents/", false)
+	add("/doc", false)
+	add("/docs/", true)
+	add("/images/", false)
+	add("/img", true)
+
+	tests := []struct {
+		path          string
+		exact, prefix string
+	}{
+		{"/", "/", "/"},
+		{"/index.html", "", "/"},
+		{"/doc", "", "/doc"},
+		{"/docs/", "/docs/", "/doc"},
+		{"/docs/a", "", "/doc"},
+		{"/documents/", "", "/documents/"},
+		{"/documents/1.html", "", "/documents/"},
+		{"/documen", "", "/doc"},
+		{"/images/a.gif", "", "/images/"},
+		{"/img", "/img", "/"},
+		{"/img/", "", "/"},
+		{"", "", ""},
+	}
+
+	name := func(l *locationHandler) string {
+		if l == nil {
+			return ""
+		}
+		return l.location
+	}
+
+	for _, test := range tests {
+		exact, prefix := root.lookup(test.path)
+		if name(exact) != test.exact || name(prefix) != test.prefix {
+			t.Errorf("%q: expected (%q, %q) got (%q, %q)",
+				test.path, test.exact, test.prefix, name(exact), name(prefix))
+		}
+	}
+}
+
+func TestPrecedence(t *testing.T) {
+	r := NewRouter()
+
+	r.Location("", "/", testHandler("prefix /"))
+	r.Location("", "/a/", testHandler("prefix /a/"))
+	r.Location("^~", "/a/b/", testHandler("^~ /a/b/"))
+	r.Location("", "/a/b/c/", testHandler("prefix /a/b/c/"))
+	r.Location("~", `\.txt$`, testHandler("~ txt"))
+	r.Location("~", `^/a/`, testHandler("~ /a/"))
+	r.Location("=", "/a/b/c/d.txt", testHandler("= d.txt"))
+
+	tests := []struct {
+		path, exp string
+	}{
+		{"/", "prefix /"},
+		{"/x.txt", "~ txt"},
+		{"/a/", "~ /a/"},
+		{"/a/x.txt", "~ txt"},
+		{"/a/b/x.txt", "^~ /a/b/"},
+		// longer prefix is not ^~, so regexps are checked in order.
+		{"/a/b/c/x.txt", "~ txt"},
+		{"/a/b/c/x", "~ /a/"},
+		{"/a/b/c/d.txt", "= d.txt"},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", test.path, nil)
+		l := r.match(req)
+		if l == nil {
+			t.Errorf("%s: expected %q got no match", test.path, test.exp)
+			continue
+		}
+		assertEqual(t, testHandler(test.exp), l.routes[0].handler)
+	}
+}
+
+func TestDuplicatePrefix(t *testing.T) {
+	r := NewRouter()
+
+	r.Location("", "/a/", testHandler("first"))
+	r.Location("", "/a/", testHandler("second"))
+
+	req, _ := http.NewRequest("GET", "/a/b", nil)
+	assertEqual(t, testHandler("first"), r.match(req).routes[0].handler)
+}
+
+var noopHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+// benchRouter builds a router with n prefix and n exact locations, and a
+// handful of regexps.
+func benchRouter(n int) *Router {
+	r := NewRouter()
+	h := noopHandler
+	for i := 0; i < n; i++ {
+		r.Location("", fmt.Sprintf("/api/v1/resource%d/", i), h)
+		r.Location("=", fmt.Sprintf("/api/v1/resource%d/index", i), h)
+	}
+	r.Location("^~", "/static/", h)
+	r.LocationRegexp(regexp.MustCompile(`\.(gif|jpg|png)$`), h)
+	r.LocationRegexp(regexp.MustCompile(`^/users/(?P<id>\d+)$`), h)
+	r.LocationPattern("/posts/:id/comments/:comment", h)
+	return r
+}
+
+func benchmarkMatch(b *testing.B, n int, path string) {
+	r := benchRouter(n)
+	req, _ := http.NewRequest("GET", path, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if r.match(req) == nil {
+			b.Fatal("expected a match")
+		}
+	}
+}
+
+func BenchmarkMatchExact10(b *testing.B)    { benchmarkMatch(b, 10, "/api/v1/resource5/index") }
+func BenchmarkMatchExact500(b *testing.B)   { benchmarkMatch(b, 500, "/api/v1/resource250/index") }
+func BenchmarkMatchPrefix10(b *testing.B)   { benchmarkMatch(b, 10, "/api/v1/resource5/1") }
+func BenchmarkMatchPrefix500(b *testing.B)  { benchmarkMatch(b, 500, "/api/v1/resource250/1") }
+func BenchmarkMatchNoRegex500(b *testing.B) { benchmarkMatch(b, 500, "/static/a/b/c.png") }
+func BenchmarkMatchRegexp500(b *testing.B)  { benchmarkMatch(b, 500, "/users/42") }
+func BenchmarkMatchPattern500(b *testing.B) { benchmarkMatch(b, 500, "/posts/42/comments/7") }
+
+func BenchmarkServeHTTP500(b *testing.B) {
+	r := benchRouter(500)
+	req, _ := http.NewRequest("GET", "/users/42", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(nil, req)
+	}
+}
+
+func BenchmarkAddLocation(b *testing.B) {
+	h := noopHandler
+	for i := 0; i < b.N; i++ {
+		r := NewRouter()
+		for j := 0; j < 500; j++ {
+			r.Location("", fmt.Sprintf("/api/v1/resource%d/", j), h)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"regexp/syntax"
+	"strings"
+)
+
+// URL builds the path of the route registered with Route.Name.
+//
+// Exact and prefix locations return their path. Pattern locations substitute
+// :name and *name segments, and regexp locations substitute named capture
+// groups, with the values in params. The regexp must describe the whole path
+// (for example ^/users/(?P<id>\d+)$); anything other than literals, anchors and
+// named groups outside of a group can't be reversed.
+//
+// URL returns an error if a param is missing, or if the result would not be
+// matched by the location with the given values. The result is escaped and
+// can be used in a link or a Location header.
+func (r *Router) URL(name string, params Params) (string, error) {
+	r.mu.RLock()
+	rt, ok := r.names[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("route %q is not registered", name)
+	}
+
+	l := rt.location
+	var path string
+	var err error
+
+	switch {
+	case l.pattern != nil:
+		path, err = l.pattern.build(params)
+	case l.regexp != nil:
+		path, err = buildRegexp(l.regexp.String(), params)
+	default:
+		path = l.location
+	}
+	if err != nil {
+		return "", fmt.Errorf("route %q: %v", name, err)
+	}
+
+	if l.pattern != nil || l.regexp != nil {
+		if err := verifyURL(l, path, params); err != nil {
+			return "", fmt.Errorf("route %q: %v", name, err)
+		}
+	}
+
+	u := url.URL{Path: path}
+	return u.EscapedPath(), nil
+}
+
+// verifyURL makes sure path matches l and captures the values in params.
+func verifyURL(l *locationHandler, path string, params Params) error {
+	var values []string
+	var ok bool
+
+	if l.pattern != nil {
+		values, ok = l.pattern.match(path)
+	} else if res := l.regexp.FindStringSubmatch(path); len(res) > 0 {
+		values, ok = res[1:], true
+	}
+	if !ok {
+		return fmt.Errorf("%q does not match the location", path)
+	}
+
+	for i, name := range l.capNames {
+		if name == "" {
+			continue
+		}
+		if v := params.ByName(name); v != values[i] {
+			return fmt.Errorf("param %q value %q does not satisfy the location", name, v)
+		}
+	}
+	return nil
+}
+
+func (p *pattern) build(params Params) (string, error) {
+	var b strings.Builder
+	for _, part := range p.parts {
+		if part.name == "" {
+			b.WriteString(part.literal)
+			continue
+		}
+
+		v, ok := lookupParam(params, part.name)
+		if !ok {
+			return "", fmt.Errorf("missing param %q", part.name)
+		}
+		if !part.catchAll && (v == "" || strings.Contains(v, "/")) {
+			return "", fmt.Errorf("param %q must be a single, non-empty path segment", part.name)
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
+
+func buildRegexp(expr string, params Params) (string, error) {
+	// case folded literals lose their case when parsed, so build with the
+	// expression as written. The result is still checked against the
+	// original.
+	re, err := syntax.Parse(strings.TrimPrefix(expr, "(?i)"), syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := buildSyntax(&b, re, params); err != nil {
+		return "", err
+	}
+
+	path := b.String()
+	if !strings.HasPrefix(path, "/") {
+		return "", fmt.Errorf("regexp %q does not describe a full path", expr)
+	}
+	return path, nil
+}
+
+func buildSyntax(b *strings.Builder, re *syntax.Regexp, params Params) error {
+	switch re.Op {
+	case syntax.OpEmptyMatch,
+		syntax.OpBeginLine, syntax.OpEndLine,
+		syntax.OpBeginText, syntax.OpEndText:
+		// nothing to write
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCapture:
+		if re.Name == "" {
+			return buildSyntax(b, re.Sub[0], params)
+		}
+		v, ok := lookupParam(params, re.Name)
+		if !ok {
+			return fmt.Errorf("missing param %q", re.Name)
+		}
+		b.WriteString(v)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if err := buildSyntax(b, sub, params); err != nil {
+				return err
+			}
+		}
+	case syntax.OpQuest, syntax.OpStar:
+		// optional parts are only written if all their params are given.
+		var opt strings.Builder
+		if err := buildSyntax(&opt, re.Sub[0], params); err == nil && hasCapture(re.Sub[0]) {
+			b.WriteString(opt.String())
+		}
+	case syntax.OpPlus:
+		return buildSyntax(b, re.Sub[0], params)
+	case syntax.OpRepeat:
+		for i := 0; i < re.Min; i++ {
+			if err := buildSyntax(b, re.Sub[0], params); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("can't build %q without a named group", re.String())
+	}
+	return nil
+}
+
+func hasCapture(re *syntax.Regexp) bool {
+	if re.Op == syntax.OpCapture && re.Name != "" {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if hasCapture(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func lookupParam(params Params, name string) (string, bool) {
+	for _, p := range params {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,81 @@
+package router
+
+import (
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	r := NewRouter()
+	h := bodyHandler("")
+
+	r.Location("=", "/", h).Name("home")
+	r.Location("^~", "/static/", h).Name("static")
+	r.Location(":", "/users/:id/posts/:post", h).Name("post")
+	r.Location(":", "/files/*path", h).Name("file")
+	r.Location("~", `^/articles/(?P<year>\d{4})/(?P<slug>[a-z-]+)(?:\.html)?$`, h).Name("article")
+	r.Location("~*", `^/Docs/(?P<page>\w+)$`, h).Name("docs")
+	r.Location("~", `^/(en|fr)/(?P<id>\d+)$`, h).Name("lang")
+	r.Location("~", `\.(?P<format>gif|jpg)$`, h).Name("image")
+
+	tests := []struct {
+		name   string
+		params Params
+		exp    string
+	}{
+		{"home", nil, "/"},
+		{"static", Params{{"ignored", "x"}}, "/static/"},
+		{"post", Params{{"id", "42"}, {"post", "7"}}, "/users/42/posts/7"},
+		{"post", Params{{"post", "7"}, {"id", "a b"}}, "/users/a%20b/posts/7"},
+		{"file", Params{{"path", "a/b.txt"}}, "/files/a/b.txt"},
+		{"file", Params{{"path", ""}}, "/files/"},
+		{"article", Params{{"year", "2017"}, {"slug", "hello-world"}}, "/articles/2017/hello-world"},
+		{"docs", Params{{"page", "index"}}, "/Docs/index"},
+	}
+
+	for _, test := range tests {
+		act, err := r.URL(test.name, test.params)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		assertEqual(t, test.exp, act)
+	}
+}
+
+func TestURLErrors(t *testing.T) {
+	r := NewRouter()
+	h := bodyHandler("")
+
+	r.Location(":", "/users/:id", h).Name("user")
+	r.Location("~", `^/articles/(?P<year>\d{4})$`, h).Name("article")
+	r.Location("~", `^/(en|fr)/(?P<id>\d+)$`, h).Name("lang")
+	r.Location("~", `\.(?P<format>gif|jpg)$`, h).Name("image")
+
+	tests := []struct {
+		name   string
+		params Params
+		exp    string
+	}{
+		{"nope", nil, `route "nope" is not registered`},
+		{"user", nil, `route "user": missing param "id"`},
+		{"user", Params{{"id", ""}}, `route "user": param "id" must be a single, non-empty path segment`},
+		{"user", Params{{"id", "a/b"}}, `route "user": param "id" must be a single, non-empty path segment`},
+		{"article", nil, `route "article": missing param "year"`},
+		{"article", Params{{"year", "17"}}, `route "article": "/articles/17" does not match the location`},
+		{"lang", Params{{"id", "1"}}, `route "lang": can't build "en|fr" without a named group`},
+		{"image", Params{{"format", "gif"}}, `route "image": regexp "\\.(?P<format>gif|jpg)$" does not describe a full path`},
+	}
+
+	for _, test := range tests {
+		_, err := r.URL(test.name, test.params)
+		if err == nil {
+			t.Errorf("%s: expected error %q", test.name, test.exp)
+			continue
+		}
+		assertEqual(t, test.exp, err.Error())
+	}
+
+	assertPanic(t, `route name "user" is already registered`, func() {
+		r.Location("=", "/user", h).Name("user")
+	})
+}
@@ -0,0 +1,229 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+func NewVirtualHosts() *VirtualHosts {
+	return &VirtualHosts{}
+}
+
+// VirtualHosts selects a Router by the Host header of the request, like the
+// nginx server_name directive[1]. It is safe for concurrent use. Servers may
+// be added while requests are being served.
+//
+// [1] http://nginx.org/en/docs/http/server_names.html
+type VirtualHosts struct {
+	// Default handles requests whose host matches no server. Defaults to
+	// the first server added, like nginx, or http.NotFound if there is none.
+	Default http.Handler
+
+	mu sync.RWMutex
+
+	exact    map[string]*Router
+	leading  []hostWildcard // *.example.com, longest first
+	trailing []hostWildcard // www.example.*, longest first
+	regexps  []hostRegexp   // in declaration order
+
+	first *Router
+}
+
+type hostWildcard struct {
+	// name without the *, eg .example.com or www.example.
+	name   string
+	router *Router
+}
+
+type hostRegexp struct {
+	regexp *regexp.Regexp
+	router *Router
+}
+
+// Server adds a new Router for the given server names and returns it. A name
+// is one of
+//
+//	example.com     the exact host
+//	*.example.com   any subdomain of example.com
+//	www.example.*   any host starting with www.example.
+//	.example.com    example.com and any of its subdomains
+//	~regexp         a host matching regexp
+//
+// Hosts are compared without their port and case insensitively. An exact name
+// wins, then the longest wildcard starting with *, then the longest wildcard
+// ending with *, then the first matching regexp.
+//
+// The part of the host matched by * is available as the Param named "*", and
+// the groups of a regexp as Params like a regexp location. Params of the host
+// come before those of the location.
+//
+// Server panics if a name is already registered or a regexp does not compile.
+func (v *VirtualHosts) Server(names ...string) *Router {
+	r := NewRouter()
+	v.AddServer(r, names...)
+	return r
+}
+
+// AddServer is like Server, but adds an existing Router.
+func (v *VirtualHosts) AddServer(r *Router, names ...string) {
+	if len(names) == 0 {
+		panic("server requires at least one name")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// check everything before anything is added.
+	var res []*regexp.Regexp
+	for _, name := range names {
+		if strings.HasPrefix(name, "~") {
+			res = append(res, regexp.MustCompile("(?i)"+name[1:]))
+			continue
+		}
+		for _, n := range expandHostName(name) {
+			if v.hasName(n) {
+				panic(fmt.Sprintf("server name %q is already registered", n))
+			}
+		}
+	}
+
+	for _, name := range names {
+		if strings.HasPrefix(name, "~") {
+			v.regexps = append(v.regexps, hostRegexp{res[0], r})
+			res = res[1:]
+			continue
+		}
+		for _, n := range expandHostName(name) {
+			v.add(n, r)
+		}
+	}
+
+	if v.first == nil {
+		v.first = r
+	}
+}
+
+// expandHostName turns .example.com into example.com and *.example.com, and
+// lower cases names.
+func expandHostName(name string) []string {
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, ".") {
+		return []string{name[1:], "*" + name}
+	}
+	return []string{name}
+}
+
+func (v *VirtualHosts) hasName(name string) bool {
+	switch {
+	case strings.HasPrefix(name, "*"):
+		return hasWildcard(v.leading, name[1:])
+	case strings.HasSuffix(name, "*"):
+		return hasWildcard(v.trailing, name[:len(name)-1])
+	}
+	_, ok := v.exact[name]
+	return ok
+}
+
+func hasWildcard(ws []hostWildcard, name string) bool {
+	for _, w := range ws {
+		if w.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *VirtualHosts) add(name string, r *Router) {
+	switch {
+	case strings.HasPrefix(name, "*"):
+		v.leading = addWildcard(v.leading, hostWildcard{name[1:], r})
+	case strings.HasSuffix(name, "*"):
+		v.trailing = addWildcard(v.trailing, hostWildcard{name[:len(name)-1], r})
+	default:
+		if v.exact == nil {
+			v.exact = make(map[string]*Router)
+		}
+		v.exact[name] = r
+	}
+}
+
+func addWildcard(ws []hostWildcard, w hostWildcard) []hostWildcard {
+	ws = append(ws, w)
+	sort.SliceStable(ws, func(i, j int) bool {
+		return len(ws[i].name) > len(ws[j].name)
+	})
+	return ws
+}
+
+func (v *VirtualHosts) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r, ps := v.Lookup(req.Host)
+	if r == nil {
+		v.serveDefault(w, req)
+		return
+	}
+
+	if len(ps) > 0 {
+		req = req.WithContext(withParams(req.Context(), ps))
+	}
+	r.ServeHTTP(w, req)
+}
+
+func (v *VirtualHosts) serveDefault(w http.ResponseWriter, req *http.Request) {
+	if v.Default != nil {
+		v.Default.ServeHTTP(w, req)
+		return
+	}
+
+	v.mu.RLock()
+	first := v.first
+	v.mu.RUnlock()
+
+	if first == nil {
+		http.NotFound(w, req)
+		return
+	}
+	first.ServeHTTP(w, req)
+}
+
+// Lookup returns the Router for host and the Params captured from it. The
+// Router is nil if no server name matches, in which case Default is used.
+func (v *VirtualHosts) Lookup(host string) (*Router, Params) {
+	host = strings.TrimSuffix(strings.ToLower(stripPort(host)), ".")
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if r, ok := v.exact[host]; ok {
+		return r, nil
+	}
+
+	for _, w := range v.leading {
+		// *.example.com does not match example.com itself.
+		if len(host) > len(w.name) && strings.HasSuffix(host, w.name) {
+			return w.router, Params{{"*", host[:len(host)-len(w.name)]}}
+		}
+	}
+
+	for _, w := range v.trailing {
+		if len(host) > len(w.name) && strings.HasPrefix(host, w.name) {
+			return w.router, Params{{"*", host[len(w.name):]}}
+		}
+	}
+
+	for _, re := range v.regexps {
+		res := re.regexp.FindStringSubmatch(host)
+		if res == nil {
+			continue
+		}
+		var ps Params
+		for i, k := range re.regexp.SubexpNames()[1:] {
+			ps = append(ps, Param{k, res[i+1]})
+		}
+		return re.router, ps
+	}
+	return nil, nil
+}
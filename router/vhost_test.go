@@ -0,0 +1,107 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// paramsHandler writes the name and the Params of the request.
+type paramsHandler string
+
+func (p paramsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "%s %v", p, ParamsFromContext(r.Context()))
+}
+
+func serveHost(h http.Handler, host, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Host = host
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestVirtualHosts(t *testing.T) {
+	v := NewVirtualHosts()
+
+	v.Server("www.example.com", "example.com").Location("", "/", paramsHandler("www"))
+	v.Server("api.example.com").Location(":", "/users/:id", paramsHandler("api"))
+	v.Server("*.example.com").Location("", "/", paramsHandler("sub"))
+	v.Server("*.admin.example.com").Location("", "/", paramsHandler("admin"))
+	v.Server("mail.*").Location("", "/", paramsHandler("mail"))
+	v.Server(`~^(?P<user>[a-z]+)\.example\.(net|org)$`).Location("", "/", paramsHandler("user"))
+	v.Server(".example.io").Location("", "/", paramsHandler("io"))
+
+	tests := []struct {
+		host, path, exp string
+	}{
+		{"www.example.com", "/", "www []"},
+		{"example.com:8080", "/", "www []"},
+		{"WWW.Example.COM.", "/", "www []"},
+		{"api.example.com", "/users/1", "api [{id 1}]"},
+		{"a.b.example.com", "/", "sub [{* a.b}]"},
+		{"x.admin.example.com", "/", "admin [{* x}]"},
+		{"mail.example.com", "/", "sub [{* mail}]"},
+		{"mail.example.net", "/", "mail [{* example.net}]"},
+		{"bob.example.org", "/", "user [{user bob} { org}]"},
+		{"example.io", "/", "io []"},
+		{"a.example.io", "/", "io [{* a}]"},
+		// falls back to the first server
+		{"other.com", "/", "www []"},
+		{"", "/", "www []"},
+	}
+
+	for _, test := range tests {
+		w := serveHost(v, test.host, test.path)
+		assertEqual(t, test.exp, w.Body.String())
+	}
+
+	v.Default = bodyHandler("default")
+	w := serveHost(v, "other.com", "/")
+	assertEqual(t, "default", w.Body.String())
+}
+
+func TestVirtualHostsEmpty(t *testing.T) {
+	w := serveHost(NewVirtualHosts(), "example.com", "/")
+	assertEqual(t, 404, w.Code)
+}
+
+func TestVirtualHostsParams(t *testing.T) {
+	v := NewVirtualHosts()
+	r := v.Server(`~^(?P<tenant>\w+)\.example\.com$`)
+	r.Location(":", "/users/:id", paramsHandler("user"))
+	r.Location("=", "/old", Redirect(301, "https://$tenant.example.net/"))
+
+	w := serveHost(v, "acme.example.com", "/users/1")
+	assertEqual(t, "user [{tenant acme} {id 1}]", w.Body.String())
+
+	w = serveHost(v, "acme.example.com", "/old")
+	assertEqual(t, "https://acme.example.net/", w.Header().Get("Location"))
+
+	req, _ := http.NewRequest("GET", "/users/2", nil)
+	req.Host = "acme.example.com"
+	rt, ps := v.Lookup(req.Host)
+	assertEqual(t, r, rt)
+	assertEqual(t, Params{{"tenant", "acme"}}, ps)
+}
+
+func TestVirtualHostsPanic(t *testing.T) {
+	v := NewVirtualHosts()
+	v.Server("example.com")
+	v.Server("*.example.net")
+
+	assertPanic(t, `server name "example.com" is already registered`, func() {
+		v.Server("a.example.com", "EXAMPLE.com")
+	})
+	assertPanic(t, `server name "*.example.net" is already registered`, func() {
+		v.Server(".example.net")
+	})
+	assertPanic(t, "server requires at least one name", func() {
+		v.Server()
+	})
+
+	// nothing was added by the failed calls
+	rt, _ := v.Lookup("a.example.com")
+	assertEqual(t, (*Router)(nil), rt)
+}
@@ -0,0 +1,302 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andreadipersio/securecookie"
+)
+
+// Codec encodes the value of a cookie before it is sent and decodes it when
+// it comes back. Decode must fail for values that were not made by Encode
+// for the same cookie name.
+type Codec interface {
+	Encode(name, value string) (string, error)
+	Decode(name, value string) (string, error)
+}
+
+// Signed is a Codec that signs values with the secret, see SignCookies. The
+// client can read the value but not change it. Values expire after 31 days,
+// like with securecookie.
+type Signed string
+
+// signedMaxAge is how long securecookie accepts a signed value.
+const signedMaxAge = 31 * 24 * time.Hour
+
+func (s Signed) Encode(name, value string) (string, error) {
+	c := &http.Cookie{Name: name, Value: value}
+	securecookie.SignCookie(c, string(s))
+	return c.Value, nil
+}
+
+// Decode returns errExpiredCookie for a value that was signed with s but is
+// too old, so that it can be told apart from a forged one.
+func (s Signed) Decode(name, value string) (string, error) {
+	v, err := securecookie.DecodeSignedValue(string(s), name, value)
+	if s.expired(name, value) {
+		return "", errExpiredCookie
+	}
+	return v, err
+}
+
+// expired reports whether value, of the form value|timestamp|signature, is
+// signed with s and older than signedMaxAge.
+func (s Signed) expired(name, value string) bool {
+	parts := strings.Split(value, "|")
+	if len(parts) != 3 {
+		return false
+	}
+	ts, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || ts >= now().Add(-signedMaxAge).Unix() {
+		return false
+	}
+
+	mac := hmac.New(sha1.New, []byte(s))
+	mac.Write([]byte(name + parts[0] + parts[1]))
+	return hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(parts[2]))
+}
+
+var (
+	errInvalidCookie = errors.New("session: invalid cookie")
+	errExpiredCookie = errors.New("session: expired cookie")
+	errNoKeys        = errors.New("session: empty keyring")
+)
+
+// Keyring is a Codec made of codecs with different keys, newest first, so
+// keys can be rotated without invalidating every cookie at once. Values are
+// encoded with the first codec and decoded by any of them. A cookie decoded
+// by an older codec is encoded again with the newest one in the response.
+//
+// To rotate, put the new key first and remove the old one once the cookies
+// it encoded have expired.
+type Keyring []Codec
+
+// SignedKeyring returns a Keyring that signs with the first secret and
+// accepts any of them.
+//
+//	codec := session.SignedKeyring(os.Getenv("SECRET"), os.Getenv("OLD_SECRET"))
+func SignedKeyring(secrets ...string) Keyring {
+	k := make(Keyring, len(secrets))
+	for i, s := range secrets {
+		k[i] = Signed(s)
+	}
+	return k
+}
+
+func (k Keyring) Encode(name, value string) (string, error) {
+	if len(k) == 0 {
+		return "", errNoKeys
+	}
+	return k[0].Encode(name, value)
+}
+
+func (k Keyring) Decode(name, value string) (string, error) {
+	v, _, err := k.DecodeKey(name, value)
+	return v, err
+}
+
+// DecodeKey is like Decode, but also returns the index of the codec that
+// decoded value. If none did, the error is that of a codec that found the
+// value expired, else that of the first codec.
+func (k Keyring) DecodeKey(name, value string) (string, int, error) {
+	if len(k) == 0 {
+		return "", -1, errNoKeys
+	}
+
+	var first error
+	for i, c := range k {
+		v, err := c.Decode(name, value)
+		if err == nil {
+			return v, i, nil
+		}
+		if i == 0 || err == errExpiredCookie {
+			first = err
+		}
+	}
+	return "", -1, first
+}
+
+// decode decodes value with codec and reports whether it must be encoded again
+// because a Keyring decoded it with an old key.
+func decode(codec Codec, name, value string) (string, bool, error) {
+	if k, ok := codec.(Keyring); ok {
+		v, i, err := k.DecodeKey(name, value)
+		return v, i > 0, err
+	}
+	v, err := codec.Decode(name, value)
+	return v, false, err
+}
+
+// Encrypted is a Codec that encrypts values with AES-GCM, so the client can
+// neither read nor change them. The expiry time is encrypted along with the
+// value, and the cookie name is authenticated so a value can't be moved to
+// another cookie.
+type Encrypted struct {
+	aead cipher.AEAD
+
+	// MaxAge is how long an encoded value is valid. If 0 values don't
+	// expire.
+	MaxAge time.Duration
+}
+
+// NewEncrypted returns an Encrypted codec. The key must be 16, 24 or 32 bytes
+// long to select AES-128, AES-192 or AES-256, and should come from a secure
+// random source.
+func NewEncrypted(key []byte, maxAge time.Duration) (*Encrypted, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypted{aead: aead, MaxAge: maxAge}, nil
+}
+
+// Encode returns base64url(nonce | seal(expiry | value)), where expiry is the
+// unix time in seconds, 0 for none.
+func (e *Encrypted) Encode(name, value string) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+8+len(value)+e.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	var exp int64
+	if e.MaxAge > 0 {
+		exp = now().Add(e.MaxAge).Unix()
+	}
+	plain := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(plain, uint64(exp))
+	plain = append(plain, value...)
+
+	b := e.aead.Seal(nonce, nonce, plain, []byte(name))
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (e *Encrypted) Decode(name, value string) (string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(b) < e.aead.NonceSize() {
+		return "", errInvalidCookie
+	}
+
+	n := e.aead.NonceSize()
+	plain, err := e.aead.Open(nil, b[:n], b[n:], []byte(name))
+	if err != nil || len(plain) < 8 {
+		return "", errInvalidCookie
+	}
+
+	if exp := int64(binary.BigEndian.Uint64(plain)); exp != 0 && now().Unix() > exp {
+		return "", errExpiredCookie
+	}
+	return string(plain[8:]), nil
+}
+
+// CodecMiddleware is like SessionMiddleware, but the cookie called name is
+// encoded with c. A cookie that fails to decode is dropped from the request,
+// and a cookie that fails to encode is not sent. See CookieStatus.
+//
+// If c is a Keyring and the cookie was decoded with an old key, the response
+// sets it again, encoded with the newest key, unless the handler sets it. The
+// request doesn't tell the other attributes of the cookie, so it gets Path /
+// and HttpOnly; use Policy inside CodecMiddleware to set others. See Rotated.
+//
+//	codec, err := session.NewEncrypted(key, 24*time.Hour)
+//	...
+//	h = session.CodecMiddleware(codec, "session")(h)
+func CodecMiddleware(c Codec, name string) func(http.Handler) http.HandlerFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			status, stale := decodeCookies(r, c, name)
+			r = withStatus(r, name, status, stale)
+			sw := &sessionWriter{
+				ResponseWriter: w,
+				codec:          c,
+				name:           name,
+			}
+			if c, err := r.Cookie(name); err == nil && stale {
+				sw.reissue = &http.Cookie{
+					Name:     name,
+					Value:    c.Value,
+					Path:     "/",
+					HttpOnly: true,
+				}
+			}
+			next.ServeHTTP(sw, r)
+
+			// never let net/http send the cookie unencoded.
+			if !sw.wroteHeader {
+				sw.WriteHeader(http.StatusOK)
+			}
+		}
+	}
+}
+
+// encodeCookies encodes the cookies called name set on w. reissue, if not nil,
+// is added unless w already sets a cookie called name.
+func encodeCookies(w http.ResponseWriter, codec Codec, name string, reissue *http.Cookie) {
+	r := &http.Response{
+		Header: w.Header(),
+	}
+	cookies := r.Cookies()
+	w.Header().Del(SetCookie)
+	for _, c := range cookies {
+		if c.Name == name {
+			reissue = nil
+		}
+	}
+	if reissue != nil {
+		cookies = append(cookies, reissue)
+	}
+	for _, c := range cookies {
+		if c.Name == name {
+			value, err := codec.Encode(c.Name, c.Value)
+			if err != nil {
+				continue
+			}
+			c.Value = value
+		}
+		http.SetCookie(w, c)
+	}
+}
+
+// decodeCookies decodes the cookies called name in r, removing those that
+// fail. The Status is Valid if any was decoded, and stale is set if one has
+// to be encoded again, see decode.
+func decodeCookies(r *http.Request, codec Codec, name string) (status Status, stale bool) {
+	cookies := r.Cookies()
+	r.Header.Del(GetCookie)
+	for _, c := range cookies {
+		if c.Name == name {
+			v, old, err := decode(codec, c.Name, c.Value)
+			switch {
+			case err == nil:
+				status = Valid
+			case err == errExpiredCookie && status != Valid:
+				status = Expired
+			case status == Missing:
+				status = Invalid
+			}
+			if err != nil {
+				continue
+			}
+			c.Value = v
+			if old {
+				stale = true
+			}
+		}
+		r.AddCookie(c)
+	}
+	return status, stale
+}
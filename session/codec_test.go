@@ -0,0 +1,253 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestEncrypted(t *testing.T) {
+	e, err := NewEncrypted(testKey, time.Hour)
+	assert.Nil(t, err)
+
+	v, err := e.Encode("s", "user=1;role=admin")
+	assert.Nil(t, err)
+	assert.NotContains(t, v, "admin")
+
+	d, err := e.Decode("s", v)
+	assert.Nil(t, err)
+	assert.Equal(t, "user=1;role=admin", d)
+
+	// values are bound to the cookie name
+	_, err = e.Decode("other", v)
+	assert.Equal(t, errInvalidCookie, err)
+
+	// tampered
+	b := []byte(v)
+	b[len(b)-2] ^= 1
+	_, err = e.Decode("s", string(b))
+	assert.Equal(t, errInvalidCookie, err)
+
+	_, err = e.Decode("s", "!!")
+	assert.Equal(t, errInvalidCookie, err)
+
+	// another key
+	e2, _ := NewEncrypted([]byte(strings.Repeat("x", 16)), time.Hour)
+	_, err = e2.Decode("s", v)
+	assert.Equal(t, errInvalidCookie, err)
+
+	_, err = NewEncrypted([]byte("short"), 0)
+	assert.NotNil(t, err)
+}
+
+func TestEncrypted_Expiry(t *testing.T) {
+	e, _ := NewEncrypted(testKey, time.Second)
+	v, _ := e.Encode("s", "a")
+	_, err := e.Decode("s", v)
+	assert.Nil(t, err)
+	advance(t, 2*time.Second)
+	_, err = e.Decode("s", v)
+	assert.Equal(t, errExpiredCookie, err)
+
+	e.MaxAge = 0
+	v, _ = e.Encode("s", "a")
+	d, err := e.Decode("s", v)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", d)
+}
+
+func TestCodecMiddleware(t *testing.T) {
+	e, _ := NewEncrypted(testKey, time.Hour)
+
+	var got string
+	h := CodecMiddleware(e, "s")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("s"); err == nil {
+			got = c.Value
+		}
+		http.SetCookie(w, &http.Cookie{Name: "s", Value: "role=admin"})
+		http.SetCookie(w, &http.Cookie{Name: "plain", Value: "x"})
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 2)
+	assert.Equal(t, "plain", cookies[1].Name)
+	assert.Equal(t, "x", cookies[1].Value)
+	assert.NotContains(t, cookies[0].Value, "admin")
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookies[0])
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "role=admin", got)
+
+	// invalid cookies are dropped
+	got = ""
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "s", Value: "role=admin"})
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "", got)
+}
+
+func TestMiddleware_Encrypted(t *testing.T) {
+	e, _ := NewEncrypted(testKey, time.Hour)
+	h := Middleware(Config{Name: "sid", Codec: e, Store: NewMemoryStore()})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := FromRequest(r)
+		if s.Get("n") == nil {
+			s.Set("n", 1)
+		} else {
+			w.Write([]byte("found"))
+		}
+	}))
+
+	c := &client{h: h}
+	c.get("/")
+	assert.Equal(t, "found", c.get("/").Body.String())
+}
+
+func TestKeyring(t *testing.T) {
+	old := SignedKeyring("old")
+	v, err := old.Encode("s", "a")
+	assert.Nil(t, err)
+
+	k := SignedKeyring("new", "old")
+	d, i, err := k.DecodeKey("s", v)
+	assert.Nil(t, err)
+	assert.Equal(t, "a", d)
+	assert.Equal(t, 1, i)
+
+	v, _ = k.Encode("s", "a")
+	_, i, _ = k.DecodeKey("s", v)
+	assert.Equal(t, 0, i)
+	_, err = old.Decode("s", v)
+	assert.NotNil(t, err)
+
+	// the old key was removed
+	_, err = SignedKeyring("newer", "new").Decode("s", "a|1|bad")
+	assert.NotNil(t, err)
+
+	_, err = Keyring{}.Encode("s", "a")
+	assert.Equal(t, errNoKeys, err)
+}
+
+func TestKeyring_Expired(t *testing.T) {
+	e1, _ := NewEncrypted(testKey, time.Second)
+	e2, _ := NewEncrypted([]byte(strings.Repeat("x", 16)), time.Hour)
+	v, _ := e1.Encode("s", "a")
+	advance(t, 2*time.Second)
+
+	k := Keyring{e2, e1}
+	_, err := k.Decode("s", v)
+	assert.Equal(t, errExpiredCookie, err)
+	_, err = k.Decode("s", "forged")
+	assert.Equal(t, errInvalidCookie, err)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "s", Value: v})
+	status, _ := decodeCookies(r, k, "s")
+	assert.Equal(t, Expired, status)
+}
+
+func TestCodecMiddleware_Rotation(t *testing.T) {
+	handler := func(set bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if set {
+				http.SetCookie(w, &http.Cookie{Name: "s", Value: "b"})
+			}
+			if c, err := r.Cookie("s"); err == nil {
+				w.Write([]byte(c.Value))
+			}
+		}
+	}
+
+	serve := func(h http.Handler, c *http.Cookie) (string, *http.Cookie) {
+		r := httptest.NewRequest("GET", "/", nil)
+		if c != nil {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		var set *http.Cookie
+		if cs := w.Result().Cookies(); len(cs) > 0 {
+			set = cs[0]
+		}
+		return w.Body.String(), set
+	}
+
+	p := Policy(CookiePolicy{
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   time.Hour,
+	}, "s")
+	before := CodecMiddleware(SignedKeyring("k1"), "s")
+	during := CodecMiddleware(SignedKeyring("k2", "k1"), "s")
+	after := CodecMiddleware(SignedKeyring("k3", "k2"), "s")
+
+	_, c1 := serve(before(handler(true)), nil)
+
+	// the k1 cookie is accepted and set again, signed with k2
+	body, c := serve(during(handler(false)), c1)
+	assert.Equal(t, "b", body)
+	if assert.NotNil(t, c) {
+		assert.Equal(t, "/", c.Path)
+		assert.True(t, c.HttpOnly)
+		assert.False(t, c.Secure)
+		assert.NotEqual(t, c1.Value, c.Value)
+		body, _ = serve(after(handler(false)), c)
+		assert.Equal(t, "b", body)
+	}
+
+	// Policy overrides the attributes
+	body, c2 := serve(during(p(handler(false))), c1)
+	assert.Equal(t, "b", body)
+	if assert.NotNil(t, c2) {
+		assert.Equal(t, "/", c2.Path)
+		assert.True(t, c2.HttpOnly)
+		assert.True(t, c2.Secure)
+		assert.Equal(t, http.SameSiteLaxMode, c2.SameSite)
+		assert.Equal(t, 3600, c2.MaxAge)
+		assert.NotEqual(t, c1.Value, c2.Value)
+	}
+
+	// the k2 cookie isn't set again
+	_, c = serve(during(p(handler(false))), c2)
+	assert.Nil(t, c)
+
+	// a cookie set by the handler wins
+	_, c = serve(during(p(handler(true))), c1)
+	assert.Equal(t, "", c.Path)
+	assert.Equal(t, 3600, c.MaxAge)
+	body, c = serve(during(p(handler(false))), c)
+	assert.Equal(t, "b", body)
+	assert.Nil(t, c)
+
+	// k1 is gone, but the re-signed cookie still works
+	body, _ = serve(after(handler(false)), c1)
+	assert.Equal(t, "", body)
+	body, _ = serve(after(handler(false)), c2)
+	assert.Equal(t, "b", body)
+}
+
+func TestMiddleware_Rotation(t *testing.T) {
+	store := NewMemoryStore()
+	h := func(k Keyring) http.Handler {
+		return testHandlerConfig(Config{Name: "sid", Codec: k, Store: store})
+	}
+
+	c := &client{h: h(SignedKeyring("k1"))}
+	c.get("/set?v=bob")
+	first := c.cookie
+
+	c.h = h(SignedKeyring("k2", "k1"))
+	assert.Equal(t, "bob", c.get("/get").Body.String())
+	assert.NotEqual(t, first.Value, c.cookie.Value)
+
+	c.h = h(SignedKeyring("k3", "k2"))
+	assert.Equal(t, "bob", c.get("/get").Body.String())
+}
@@ -0,0 +1,165 @@
+package session
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HostPrefix is the cookie name prefix that makes browsers require Secure,
+// Path / and no Domain, so the cookie can't be set by a subdomain.
+const HostPrefix = "__Host-"
+
+// CookiePolicy sets the attributes of session cookies, overriding those set
+// by handlers. Zero fields leave the attribute alone.
+type CookiePolicy struct {
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+	Domain   string
+	Path     string
+
+	// MaxAge is the Max-Age of the cookie. Cookies being deleted, with a
+	// negative Max-Age or an Expires in the past, are left alone.
+	MaxAge time.Duration
+
+	// HostPrefix requires the cookie name to start with __Host-, and sets
+	// Secure, Path / and no Domain as browsers require for it.
+	HostPrefix bool
+
+	// Sliding sets the cookie again on each request that has it, so that
+	// MaxAge starts over on activity. The request doesn't tell the other
+	// attributes of the cookie, so unless the policy sets them the cookie
+	// gets Path / and HttpOnly.
+	Sliding bool
+}
+
+// Apply sets the attributes of c. SameSite None implies Secure, as browsers
+// reject it otherwise.
+func (p *CookiePolicy) Apply(c *http.Cookie) {
+	if p.Secure {
+		c.Secure = true
+	}
+	if p.HttpOnly {
+		c.HttpOnly = true
+	}
+	if p.SameSite != 0 {
+		c.SameSite = p.SameSite
+	}
+	if p.Domain != "" {
+		c.Domain = p.Domain
+	}
+	if p.Path != "" {
+		c.Path = p.Path
+	}
+	if p.MaxAge > 0 && !deleted(c) {
+		c.MaxAge = int(p.MaxAge / time.Second)
+		c.Expires = time.Time{}
+	}
+	if p.HostPrefix {
+		c.Secure = true
+		c.Domain = ""
+		c.Path = "/"
+	}
+	if c.SameSite == http.SameSiteNoneMode {
+		c.Secure = true
+	}
+}
+
+// deleted reports whether c tells the browser to delete the cookie.
+func deleted(c *http.Cookie) bool {
+	return c.MaxAge < 0 || c.MaxAge == 0 && !c.Expires.IsZero() && c.Expires.Before(time.Now())
+}
+
+// check panics if name can't be used with p.
+func (p *CookiePolicy) check(name string) {
+	if p.HostPrefix && !strings.HasPrefix(name, HostPrefix) {
+		panic("session: cookie " + name + " must start with " + HostPrefix)
+	}
+}
+
+type policyWriter struct {
+	http.ResponseWriter
+	policy *CookiePolicy
+	name   string
+
+	// slide is set again if the handler doesn't set it.
+	slide *http.Cookie
+
+	wroteHeader bool
+}
+
+func (w *policyWriter) Write(buf []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(buf)
+}
+
+func (w *policyWriter) WriteHeader(i int) {
+	w.wroteHeader = true
+
+	r := &http.Response{
+		Header: w.Header(),
+	}
+	cookies := r.Cookies()
+	w.Header().Del(SetCookie)
+	slide := w.slide
+	for _, c := range cookies {
+		if c.Name == w.name {
+			w.policy.Apply(c)
+			slide = nil
+		}
+		http.SetCookie(w, c)
+	}
+	if slide != nil {
+		w.policy.Apply(slide)
+		http.SetCookie(w, slide)
+	}
+	w.ResponseWriter.WriteHeader(i)
+}
+
+// Policy returns a middleware that applies p to the cookie called name set by
+// handlers. Use it inside SessionMiddleware or CodecMiddleware, so that the
+// cookies it sets are encoded:
+//
+//	h = session.Policy(session.CookiePolicy{
+//		Secure:   true,
+//		HttpOnly: true,
+//		SameSite: http.SameSiteLaxMode,
+//		MaxAge:   time.Hour,
+//		Sliding:  true,
+//	}, "session")(h)
+//	h = session.SessionMiddleware(secret, "session")(h)
+//
+// A cookie that CodecMiddleware decoded with an old key of a Keyring is set
+// again with the attributes of p, like a Sliding one. For
+// Middleware use Config.Cookie instead. Policy panics if p.HostPrefix is
+// set and name doesn't start with __Host-.
+func Policy(p CookiePolicy, name string) func(http.Handler) http.HandlerFunc {
+	p.check(name)
+
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			pw := &policyWriter{
+				ResponseWriter: w,
+				policy:         &p,
+				name:           name,
+			}
+			// invalid cookies have been removed by the session middleware.
+			if c, err := r.Cookie(name); err == nil && (p.Sliding || Rotated(r, name)) {
+				pw.slide = &http.Cookie{
+					Name:     name,
+					Value:    c.Value,
+					Path:     "/",
+					HttpOnly: true,
+				}
+			}
+			next.ServeHTTP(pw, r)
+
+			if !pw.wroteHeader {
+				pw.WriteHeader(http.StatusOK)
+			}
+		}
+	}
+}
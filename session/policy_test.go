@@ -0,0 +1,181 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCookiePolicy_Apply(t *testing.T) {
+	p := &CookiePolicy{
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Domain:   "example.com",
+		Path:     "/app",
+		MaxAge:   time.Hour,
+	}
+
+	c := &http.Cookie{Name: "s", Value: "a", Path: "/", Expires: time.Now().Add(time.Minute)}
+	p.Apply(c)
+	assert.True(t, c.Secure)
+	assert.True(t, c.HttpOnly)
+	assert.Equal(t, http.SameSiteNoneMode, c.SameSite)
+	assert.Equal(t, "example.com", c.Domain)
+	assert.Equal(t, "/app", c.Path)
+	assert.Equal(t, 3600, c.MaxAge)
+	assert.True(t, c.Expires.IsZero())
+
+	// deleted cookies stay deleted
+	c = &http.Cookie{Name: "s", MaxAge: -1}
+	p.Apply(c)
+	assert.Equal(t, -1, c.MaxAge)
+	c = &http.Cookie{Name: "s", Expires: time.Unix(0, 0)}
+	p.Apply(c)
+	assert.Equal(t, 0, c.MaxAge)
+	assert.Equal(t, time.Unix(0, 0), c.Expires)
+	assert.Equal(t, "s=; Path=/app; Domain=example.com; Expires=Thu, 01 Jan 1970 00:00:00 GMT; HttpOnly; Secure; SameSite=None", c.String())
+
+	p = &CookiePolicy{HostPrefix: true}
+	c = &http.Cookie{Name: "__Host-s", Domain: "example.com", Path: "/app"}
+	p.Apply(c)
+	assert.True(t, c.Secure)
+	assert.Equal(t, "", c.Domain)
+	assert.Equal(t, "/", c.Path)
+	assert.False(t, c.HttpOnly)
+}
+
+func TestPolicy(t *testing.T) {
+	p := CookiePolicy{
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		MaxAge:   time.Hour,
+		Sliding:  true,
+	}
+	h := SessionMiddleware("secret", "s")(Policy(p, "s")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "s", Value: "bob"})
+			http.SetCookie(w, &http.Cookie{Name: "other", Value: "x"})
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "s", MaxAge: -1})
+		}
+		if c, err := r.Cookie("s"); err == nil {
+			w.Write([]byte(c.Value))
+		}
+	})))
+
+	serve := func(path string, c *http.Cookie) (*httptest.ResponseRecorder, map[string]*http.Cookie) {
+		r := httptest.NewRequest("GET", path, nil)
+		if c != nil {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		cookies := map[string]*http.Cookie{}
+		for _, c := range w.Result().Cookies() {
+			cookies[c.Name] = c
+		}
+		return w, cookies
+	}
+
+	_, cookies := serve("/login", nil)
+	s := cookies["s"]
+	if assert.NotNil(t, s) {
+		assert.True(t, s.Secure)
+		assert.True(t, s.HttpOnly)
+		assert.Equal(t, http.SameSiteLaxMode, s.SameSite)
+		assert.Equal(t, 3600, s.MaxAge)
+	}
+	// other cookies are left alone
+	assert.False(t, cookies["other"].Secure)
+
+	// activity refreshes the cookie, signed again
+	w, cookies := serve("/", s)
+	assert.Equal(t, "bob", w.Body.String())
+	if assert.NotNil(t, cookies["s"]) {
+		assert.Equal(t, 3600, cookies["s"].MaxAge)
+		d, err := Signed("secret").Decode("s", cookies["s"].Value)
+		assert.Nil(t, err)
+		assert.Equal(t, "bob", d)
+	}
+
+	// the cookie is refreshed on its path, not that of the request
+	w, cookies = serve("/a/b/c", s)
+	assert.Equal(t, "bob", w.Body.String())
+	if assert.NotNil(t, cookies["s"]) {
+		assert.Equal(t, "/", cookies["s"].Path)
+		assert.True(t, cookies["s"].HttpOnly)
+	}
+
+	// no cookie, nothing to refresh
+	_, cookies = serve("/", nil)
+	assert.Empty(t, cookies)
+
+	// forged cookies are not refreshed
+	_, cookies = serve("/", &http.Cookie{Name: "s", Value: "bob"})
+	assert.Empty(t, cookies)
+
+	_, cookies = serve("/logout", s)
+	assert.True(t, cookies["s"].MaxAge < 0)
+}
+
+func TestPolicy_SlidingDefaults(t *testing.T) {
+	h := Policy(CookiePolicy{MaxAge: time.Hour, Sliding: true}, "s")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	r := httptest.NewRequest("GET", "/a/b/c", nil)
+	r.AddCookie(&http.Cookie{Name: "s", Value: "a"})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	cookies := w.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, "/", cookies[0].Path)
+		assert.True(t, cookies[0].HttpOnly)
+		assert.Equal(t, 3600, cookies[0].MaxAge)
+	}
+}
+
+func TestPolicy_HostPrefix(t *testing.T) {
+	assert.Panics(t, func() { Policy(CookiePolicy{HostPrefix: true}, "s") })
+	assert.NotPanics(t, func() { Policy(CookiePolicy{HostPrefix: true}, "__Host-s") })
+	assert.Panics(t, func() {
+		Middleware(Config{Name: "sid", Secret: "x", Store: NewMemoryStore(), Cookie: CookiePolicy{HostPrefix: true}})
+	})
+}
+
+func TestMiddleware_Sliding(t *testing.T) {
+	store := NewMemoryStore()
+	h := testHandlerConfig(Config{
+		Name:   "__Host-sid",
+		Secret: "secret",
+		Store:  store,
+		MaxAge: time.Hour,
+		Cookie: CookiePolicy{
+			HostPrefix: true,
+			SameSite:   http.SameSiteStrictMode,
+			Sliding:    true,
+		},
+	})
+
+	c := &client{h: h}
+	c.get("/set?v=bob")
+	first := c.cookie
+	if assert.NotNil(t, first) {
+		assert.True(t, first.Secure)
+		assert.True(t, first.HttpOnly)
+		assert.Equal(t, http.SameSiteStrictMode, first.SameSite)
+		assert.Equal(t, 3600, first.MaxAge)
+	}
+
+	// reading the session sets the cookie again
+	r := httptest.NewRequest("GET", "/get", nil)
+	r.AddCookie(first)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, "bob", w.Body.String())
+	assert.Len(t, w.Result().Cookies(), 1)
+}
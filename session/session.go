@@ -10,6 +10,17 @@ type sessionWriter struct {
 	http.ResponseWriter
 	secret, name string
 
+	// codec encodes the cookie instead of signing it with secret, see
+	// CodecMiddleware.
+	codec Codec
+
+	// reissue is set again if the handler doesn't set it, see Keyring.
+	reissue *http.Cookie
+
+	// session is saved before the headers are written, nil for
+	// SessionMiddleware.
+	session *Session
+
 	wroteHeader bool
 }
 
@@ -22,14 +33,23 @@ func (w *sessionWriter) Write(buf []byte) (int, error) {
 
 func (w *sessionWriter) WriteHeader(i int) {
 	w.wroteHeader = true
-	SignCookies(w, w.secret, w.name)
+	if w.session != nil {
+		w.session.save(w)
+	}
+	if w.codec != nil {
+		encodeCookies(w, w.codec, w.name, w.reissue)
+	} else {
+		SignCookies(w, w.secret, w.name)
+	}
 	w.ResponseWriter.WriteHeader(i)
 }
 
+// SessionMiddleware signs the cookie called name with secret. To rotate the
+// secret use CodecMiddleware with a SignedKeyring.
 func SessionMiddleware(secret, name string) func(http.Handler) http.HandlerFunc {
 	return func(next http.Handler) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			DecodeCookies(r, secret, name)
+			r = withStatus(r, name, DecodeCookies(r, secret, name), false)
 			w = &sessionWriter{
 				ResponseWriter: w,
 				secret:         secret,
@@ -45,6 +65,8 @@ const (
 	GetCookie = "Cookie"
 )
 
+// SignCookies signs the cookies called name set on w. Their other attributes
+// are kept; use Policy to enforce them.
 func SignCookies(w http.ResponseWriter, secret, name string) {
 	r := &http.Response{
 		Header: w.Header(),
@@ -59,15 +81,10 @@ func SignCookies(w http.ResponseWriter, secret, name string) {
 	}
 }
 
-func DecodeCookies(r *http.Request, secret, name string) {
-	cookies := r.Cookies()
-	r.Header.Del(GetCookie)
-	for _, c := range cookies {
-		if c.Name == name {
-			if value, err := securecookie.DecodeSignedValue(secret, c.Name, c.Value); err == nil {
-				c.Value = value
-			}
-		}
-		r.AddCookie(c)
-	}
+// DecodeCookies verifies the signature of the cookies called name in r and
+// replaces their values with the signed ones. Cookies that fail verification
+// are removed from r.
+func DecodeCookies(r *http.Request, secret, name string) Status {
+	status, _ := decodeCookies(r, Signed(secret), name)
+	return status
 }
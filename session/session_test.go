@@ -0,0 +1,336 @@
+package session
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// client keeps the session cookie between requests.
+type client struct {
+	h      http.Handler
+	cookie *http.Cookie
+}
+
+func (c *client) get(path string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", path, nil)
+	if c.cookie != nil {
+		r.AddCookie(c.cookie)
+	}
+	w := httptest.NewRecorder()
+	c.h.ServeHTTP(w, r)
+	for _, ck := range w.Result().Cookies() {
+		if ck.MaxAge < 0 {
+			c.cookie = nil
+		} else {
+			c.cookie = ck
+		}
+	}
+	return w
+}
+
+func testHandler(store Store) http.Handler {
+	return testHandlerConfig(Config{
+		Name:   "sid",
+		Secret: "secret",
+		Store:  store,
+		MaxAge: time.Hour,
+	})
+}
+
+func testHandlerConfig(c Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/set", func(w http.ResponseWriter, r *http.Request) {
+		FromRequest(r).Set("user", r.URL.Query().Get("v"))
+	})
+	mux.HandleFunc("/get", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, FromRequest(r).Get("user"))
+	})
+	mux.HandleFunc("/delete", func(w http.ResponseWriter, r *http.Request) {
+		FromRequest(r).Delete("user")
+	})
+	mux.HandleFunc("/flash", func(w http.ResponseWriter, r *http.Request) {
+		FromRequest(r).Flash("msg", "saved")
+	})
+	mux.HandleFunc("/msg", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, FromRequest(r).Get("msg"))
+	})
+	mux.HandleFunc("/destroy", func(w http.ResponseWriter, r *http.Request) {
+		FromRequest(r).Destroy()
+	})
+	mux.HandleFunc("/regenerate", func(w http.ResponseWriter, r *http.Request) {
+		FromRequest(r).Regenerate()
+		fmt.Fprint(w, "ok")
+	})
+	mux.HandleFunc("/id", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, FromRequest(r).ID())
+	})
+
+	return Middleware(c)(mux)
+}
+
+func testStore(t *testing.T, store Store) {
+	c := &client{h: testHandler(store)}
+
+	// nothing is saved until the session changes.
+	w := c.get("/get")
+	assert.Equal(t, "<nil>", w.Body.String())
+	assert.Nil(t, c.cookie)
+
+	c.get("/set?v=bob")
+	if assert.NotNil(t, c.cookie) {
+		assert.Equal(t, 3600, c.cookie.MaxAge)
+		assert.True(t, c.cookie.HttpOnly)
+	}
+	assert.Equal(t, "bob", c.get("/get").Body.String())
+
+	// flashes are read once
+	c.get("/flash")
+	assert.Equal(t, "saved", c.get("/msg").Body.String())
+	assert.Equal(t, "<nil>", c.get("/msg").Body.String())
+
+	// a new id keeps the values and the old id stops working.
+	old := c.get("/id").Body.String()
+	oldCookie := c.cookie
+	w = c.get("/regenerate")
+	assert.Equal(t, "ok", w.Body.String())
+	assert.NotEqual(t, old, c.get("/id").Body.String())
+	assert.Equal(t, "bob", c.get("/get").Body.String())
+	_, err := store.Load(old)
+	assert.Equal(t, ErrNotFound, err)
+	assert.Equal(t, "<nil>", (&client{h: c.h, cookie: oldCookie}).get("/get").Body.String())
+
+	c.get("/delete")
+	assert.Equal(t, "<nil>", c.get("/get").Body.String())
+
+	c.get("/set?v=alice")
+	id := c.get("/id").Body.String()
+	c.get("/destroy")
+	assert.Nil(t, c.cookie)
+	_, err = store.Load(id)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	testStore(t, newFileStore(t.TempDir()))
+}
+
+// newFileStore returns a FileStore that doesn't sweep in the background,
+// which would race with advance.
+func newFileStore(dir string) *FileStore {
+	s := NewFileStore(dir)
+	s.lastSweep = now()
+	return s
+}
+
+// advance moves the clock of the package forward by d until the test ends.
+func advance(t *testing.T, d time.Duration) {
+	old := now
+	now = func() time.Time { return old().Add(d) }
+	t.Cleanup(func() { now = old })
+}
+
+func TestStore_Expiry(t *testing.T) {
+	for _, store := range []Store{NewMemoryStore(), newFileStore(t.TempDir())} {
+		assert.Nil(t, store.Save("a", []byte("x"), -time.Second))
+		b, err := store.Load("a")
+		assert.Nil(t, err)
+		assert.Equal(t, "x", string(b))
+
+		assert.Nil(t, store.Save("a", []byte("x"), time.Second))
+		advance(t, 2*time.Second)
+		_, err = store.Load("a")
+		assert.Equal(t, ErrNotFound, err)
+	}
+}
+
+func TestStore_Cleanup(t *testing.T) {
+	m := NewMemoryStore()
+	m.Save("a", []byte("x"), time.Second)
+	m.Save("b", []byte("x"), time.Hour)
+	m.Save("c", []byte("x"), 0)
+	advance(t, 2*time.Second)
+	m.Cleanup()
+	assert.Len(t, m.sessions, 2)
+	assert.NotContains(t, m.sessions, "a")
+
+	// Save sweeps once the interval has passed
+	m.Save("d", []byte("x"), time.Second)
+	advance(t, 2*time.Second)
+	m.Save("e", []byte("x"), 0)
+	assert.Contains(t, m.sessions, "d")
+	advance(t, sweepInterval)
+	m.Save("e", []byte("x"), 0)
+	assert.NotContains(t, m.sessions, "d")
+
+	dir := t.TempDir()
+	f := newFileStore(dir)
+	f.Save("a", []byte("x"), time.Second)
+	f.Save("b", []byte("x"), time.Hour)
+	f.Save("c", []byte("x"), 0)
+	advance(t, 2*time.Second)
+	assert.Nil(t, f.Cleanup())
+
+	files, _ := filepath.Glob(filepath.Join(dir, "session_*"))
+	assert.Equal(t, []string{
+		filepath.Join(dir, "session_b"),
+		filepath.Join(dir, "session_c"),
+	}, files)
+}
+
+func TestFileStore_InvalidID(t *testing.T) {
+	s := NewFileStore(t.TempDir())
+	assert.Equal(t, errInvalidID, s.Save("../a", nil, 0))
+	_, err := s.Load("../a")
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestMiddleware_BadCookie(t *testing.T) {
+	store := NewMemoryStore()
+	h := testHandler(store)
+
+	c := &client{h: h}
+	c.get("/set?v=bob")
+	id := c.get("/id").Body.String()
+
+	// the cookie isn't signed
+	c = &client{h: h, cookie: &http.Cookie{Name: "sid", Value: id}}
+	assert.Equal(t, "<nil>", c.get("/get").Body.String())
+}
+
+func TestMiddleware_Panics(t *testing.T) {
+	assert.Panics(t, func() { Middleware(Config{Name: "sid", Secret: "x"}) })
+}
+
+// oldSigned returns value signed with secret for the cookie s 32 days ago.
+func oldSigned(secret, value string) string {
+	v := base64.URLEncoding.EncodeToString([]byte(value))
+	ts := strconv.FormatInt(time.Now().Add(-32*24*time.Hour).Unix(), 10)
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte("s" + v + ts))
+	return v + "|" + ts + "|" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestDecodeCookies(t *testing.T) {
+	signed, _ := Signed("secret").Encode("s", "a")
+	e, _ := NewEncrypted(testKey, time.Second)
+	expired, _ := e.Encode("s", "a")
+	advance(t, 2*time.Second)
+
+	tests := []struct {
+		codec  Codec
+		values []string
+		status Status
+		exp    string
+	}{
+		{Signed("secret"), nil, Missing, ""},
+		{Signed("secret"), []string{signed}, Valid, "a"},
+		{Signed("secret"), []string{"forged"}, Invalid, ""},
+		{Signed("other"), []string{signed}, Invalid, ""},
+		{Signed("secret"), []string{"forged", signed}, Valid, "a"},
+		{e, []string{expired}, Expired, ""},
+		{e, []string{"forged", expired}, Expired, ""},
+		{Signed("secret"), []string{oldSigned("secret", "a")}, Expired, ""},
+		{Signed("other"), []string{oldSigned("secret", "a")}, Invalid, ""},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(&http.Cookie{Name: "other", Value: "x"})
+		for _, v := range test.values {
+			r.AddCookie(&http.Cookie{Name: "s", Value: v})
+		}
+
+		status, _ := decodeCookies(r, test.codec, "s")
+		assert.Equal(t, test.status, status, test.values)
+
+		// invalid cookies are gone, others are kept
+		var got string
+		if c, err := r.Cookie("s"); err == nil {
+			got = c.Value
+		}
+		assert.Equal(t, test.exp, got, test.values)
+		assert.Len(t, r.Cookies(), 1+len(test.exp))
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "s", Value: "forged"})
+	assert.Equal(t, Invalid, DecodeCookies(r, "secret", "s"))
+	assert.Empty(t, r.Cookies())
+}
+
+func TestOnInvalid(t *testing.T) {
+	var logged []Status
+	h := SessionMiddleware("secret", "s")(OnInvalid("s", func(w http.ResponseWriter, r *http.Request, s Status) bool {
+		logged = append(logged, s)
+		http.Error(w, "400 bad request", http.StatusBadRequest)
+		return false
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CookieStatus(r, "s").String()))
+	})))
+
+	signed, _ := Signed("secret").Encode("s", "a")
+	for _, test := range []struct {
+		value string
+		code  int
+		exp   string
+	}{
+		{"", 200, "missing"},
+		{signed, 200, "valid"},
+		{"forged", 400, "400 bad request\n"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.value != "" {
+			r.AddCookie(&http.Cookie{Name: "s", Value: test.value})
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		assert.Equal(t, test.code, w.Code)
+		assert.Equal(t, test.exp, w.Body.String())
+	}
+	assert.Equal(t, []Status{Invalid}, logged)
+}
+
+func TestMiddleware_Status(t *testing.T) {
+	store := NewMemoryStore()
+	h := testHandler(store)
+	status := Middleware(Config{Name: "sid", Secret: "secret", Store: store})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CookieStatus(r, "sid").String()))
+		// only a valid cookie is left in the request
+		if _, err := r.Cookie("sid"); err == nil {
+			w.Write([]byte(" cookie"))
+		}
+	}))
+
+	c := &client{h: h}
+	c.get("/set?v=bob")
+	id := c.get("/id").Body.String()
+
+	c.h = status
+	assert.Equal(t, "valid cookie", c.get("/").Body.String())
+
+	store.Delete(id)
+	assert.Equal(t, "expired", c.get("/").Body.String())
+
+	c.cookie = &http.Cookie{Name: "sid", Value: id}
+	assert.Equal(t, "invalid", c.get("/").Body.String())
+
+	// signed, but not an id
+	v, _ := Signed("secret").Encode("sid", "../x")
+	c.cookie = &http.Cookie{Name: "sid", Value: v}
+	assert.Equal(t, "invalid", c.get("/").Body.String())
+}
@@ -0,0 +1,347 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Config configures Middleware.
+type Config struct {
+	// Name is the name of the cookie holding the session id.
+	Name string
+
+	// Secret signs the cookie, see SignCookies.
+	Secret string
+
+	// Codec encodes the cookie instead of signing it with Secret, eg an
+	// Encrypted codec or a Keyring to rotate keys.
+	Codec Codec
+
+	// Store keeps the session data, eg NewMemoryStore() or NewFileStore.
+	Store Store
+
+	// MaxAge is how long a session lasts after it was last saved. If 0 the
+	// cookie lasts until the browser is closed and the Store keeps the
+	// session until it is destroyed.
+	MaxAge time.Duration
+
+	// Path is the path of the cookie. Defaults to /.
+	Path string
+
+	// Cookie sets the other attributes of the cookie, and may override
+	// MaxAge and Path. The cookie is always HttpOnly. If Cookie.Sliding is
+	// set, every request with a session saves it again, so that both the
+	// cookie and the session in the Store expire MaxAge after the last
+	// request.
+	Cookie CookiePolicy
+
+	// OnError is called if the session can't be saved. Defaults to logging
+	// the error.
+	OnError func(r *http.Request, err error)
+}
+
+// Middleware returns a middleware that keeps a Session for each client in
+// c.Store. The cookie only holds the id of the session. Handlers get the
+// session with FromRequest; it is saved before the response headers are
+// written if it was changed, so it must be changed before the first Write or
+// WriteHeader. A cookie that is not Valid, see CookieStatus, gets a new
+// session.
+//
+//	s := session.FromRequest(r)
+//	s.Set("user", id)
+//
+// Values are encoded with encoding/gob, so types other than the basic ones
+// must be registered with gob.Register.
+//
+// Middleware panics if Name, Store or one of Secret and Codec is not set, or
+// if the Cookie policy requires a __Host- name.
+func Middleware(c Config) func(http.Handler) http.HandlerFunc {
+	if c.Name == "" || c.Secret == "" && c.Codec == nil || c.Store == nil {
+		panic("session: Config requires Name, Secret or Codec, and Store")
+	}
+	if c.Codec == nil {
+		c.Codec = Signed(c.Secret)
+	}
+	if c.Path == "" {
+		c.Path = "/"
+	}
+	c.Cookie.check(c.Name)
+
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			s, status := c.load(r)
+			r = withStatus(r, c.Name, status, false)
+			r = r.WithContext(context.WithValue(r.Context(), sessionKey{}, s))
+			s.r = r
+
+			sw := &sessionWriter{
+				ResponseWriter: w,
+				codec:          c.Codec,
+				name:           c.Name,
+				session:        s,
+			}
+			next.ServeHTTP(sw, r)
+
+			// a handler that writes nothing still gets its session saved.
+			if !sw.wroteHeader {
+				sw.WriteHeader(http.StatusOK)
+			}
+		}
+	}
+}
+
+// load returns the session of r. The cookie is decoded in r, see
+// decodeCookies, and removed from it if it has no session.
+func (c *Config) load(r *http.Request) (*Session, Status) {
+	s := &Session{config: c, values: map[string]interface{}{}}
+
+	status, stale := decodeCookies(r, c.Codec, c.Name)
+	if status != Valid {
+		return s, status
+	}
+	cookie, _ := r.Cookie(c.Name)
+	id := cookie.Value
+	if !validID(id) {
+		removeCookies(r, c.Name)
+		return s, Invalid
+	}
+
+	b, err := c.Store.Load(id)
+	if err == ErrNotFound {
+		removeCookies(r, c.Name)
+		return s, Expired
+	}
+	if err != nil {
+		c.onError(r, err)
+		removeCookies(r, c.Name)
+		return s, Missing
+	}
+
+	var d sessionData
+	if gob.NewDecoder(bytes.NewReader(b)).Decode(&d) != nil {
+		removeCookies(r, c.Name)
+		return s, Invalid
+	}
+	s.id = id
+	if d.Values != nil {
+		s.values = d.Values
+	}
+	s.flashes = d.Flashes
+	// set the cookie again with the newest key or a new expiry.
+	s.changed = stale || c.Cookie.Sliding
+	return s, Valid
+}
+
+// removeCookies removes the cookies called name from r.
+func removeCookies(r *http.Request, name string) {
+	cookies := r.Cookies()
+	r.Header.Del(GetCookie)
+	for _, c := range cookies {
+		if c.Name != name {
+			r.AddCookie(c)
+		}
+	}
+}
+
+func (c *Config) onError(r *http.Request, err error) {
+	if c.OnError != nil {
+		c.OnError(r, err)
+		return
+	}
+	log.Printf("session: %v", err)
+}
+
+type sessionKey struct{}
+
+// FromRequest returns the Session of r, or nil if r did not go through
+// Middleware.
+func FromRequest(r *http.Request) *Session {
+	s, _ := r.Context().Value(sessionKey{}).(*Session)
+	return s
+}
+
+// sessionData is what is saved in the Store.
+type sessionData struct {
+	Values  map[string]interface{}
+	Flashes map[string]bool
+}
+
+// Session is the server side state of a client, see Middleware. It is safe
+// for concurrent use.
+type Session struct {
+	config *Config
+	r      *http.Request
+
+	mu      sync.Mutex
+	id      string
+	values  map[string]interface{}
+	flashes map[string]bool // keys set with Flash
+
+	changed   bool
+	destroyed bool
+	oldID     string // deleted from the Store on save, see Regenerate
+}
+
+// ID returns the id of the session, or "" if it is new and hasn't been saved
+// yet.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// Get returns the value for key, or nil. A value set with Flash is deleted
+// once it has been read.
+func (s *Session) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := s.values[key]
+	if s.flashes[key] {
+		delete(s.values, key)
+		delete(s.flashes, key)
+		s.changed = true
+	}
+	return v
+}
+
+// Set sets the value for key.
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+	delete(s.flashes, key)
+	s.changed = true
+}
+
+// Delete deletes the value for key.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		delete(s.flashes, key)
+		s.changed = true
+	}
+}
+
+// Flash sets a value for key that is deleted once it has been read with Get,
+// usually by the next request, eg a message to show after a redirect.
+func (s *Session) Flash(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+	if s.flashes == nil {
+		s.flashes = make(map[string]bool)
+	}
+	s.flashes[key] = true
+	s.changed = true
+}
+
+// Destroy deletes all values and removes the session from the Store. The
+// cookie is expired.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values = map[string]interface{}{}
+	s.flashes = nil
+	s.destroyed = true
+	s.changed = true
+}
+
+// Regenerate gives the session a new id, keeping its values. Call it when the
+// privileges of the client change, eg on login, to prevent session fixation.
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.id != "" && s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = ""
+	s.destroyed = false
+	s.changed = true
+}
+
+// save saves the session if it changed and sets the cookie on w.
+func (s *Session) save(w http.ResponseWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.changed {
+		return
+	}
+	s.changed = false
+	c := s.config
+
+	if s.oldID != "" {
+		if err := c.Store.Delete(s.oldID); err != nil {
+			c.onError(s.r, err)
+		}
+		s.oldID = ""
+	}
+
+	cookie := &http.Cookie{
+		Name:     c.Name,
+		Path:     c.Path,
+		HttpOnly: true,
+	}
+
+	if s.destroyed {
+		if s.id != "" {
+			if err := c.Store.Delete(s.id); err != nil {
+				c.onError(s.r, err)
+			}
+		}
+		s.id = ""
+		cookie.MaxAge = -1
+		c.Cookie.Apply(cookie)
+		http.SetCookie(w, cookie)
+		return
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(sessionData{s.values, s.flashes})
+	if err != nil {
+		c.onError(s.r, err)
+		return
+	}
+
+	if s.id == "" {
+		if s.id, err = newID(); err != nil {
+			c.onError(s.r, err)
+			return
+		}
+	}
+	if err := c.Store.Save(s.id, buf.Bytes(), c.MaxAge); err != nil {
+		c.onError(s.r, err)
+		return
+	}
+
+	cookie.Value = s.id
+	if c.MaxAge > 0 {
+		cookie.MaxAge = int(c.MaxAge / time.Second)
+	}
+	c.Cookie.Apply(cookie)
+	http.SetCookie(w, cookie)
+}
+
+// newID returns a random session id.
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,99 @@
+package session
+
+import (
+	"context"
+	"net/http"
+)
+
+// Status is the result of verifying a session cookie.
+type Status int
+
+const (
+	// Missing means the request has no cookie with the name.
+	Missing Status = iota
+	// Valid means the cookie was verified.
+	Valid
+	// Invalid means the cookie was forged or corrupted. It is removed from
+	// the request.
+	Invalid
+	// Expired means the cookie was valid but has expired, or its session is
+	// no longer in the Store. It is removed from the request.
+	Expired
+)
+
+func (s Status) String() string {
+	switch s {
+	case Missing:
+		return "missing"
+	case Valid:
+		return "valid"
+	case Invalid:
+		return "invalid"
+	case Expired:
+		return "expired"
+	}
+	return "unknown"
+}
+
+type statusKey struct{}
+
+// cookieState is what the session middleware found out about a cookie.
+type cookieState struct {
+	status Status
+	// stale is set if the cookie was decoded with an old key of a Keyring.
+	stale bool
+}
+
+// withStatus returns a shallow copy of r that records the Status of the
+// cookie called name.
+func withStatus(r *http.Request, name string, s Status, stale bool) *http.Request {
+	old, _ := r.Context().Value(statusKey{}).(map[string]cookieState)
+	m := make(map[string]cookieState, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	m[name] = cookieState{s, stale}
+	return r.WithContext(context.WithValue(r.Context(), statusKey{}, m))
+}
+
+// CookieStatus returns the Status of the session cookie called name, as
+// verified by SessionMiddleware, CodecMiddleware or Middleware. It is Missing
+// if r did not go through one of them.
+func CookieStatus(r *http.Request, name string) Status {
+	m, _ := r.Context().Value(statusKey{}).(map[string]cookieState)
+	return m[name].status
+}
+
+// Rotated reports whether CodecMiddleware decoded the cookie called name with
+// an old key of a Keyring, so it is set again in the response to be encoded
+// with the newest key.
+func Rotated(r *http.Request, name string) bool {
+	m, _ := r.Context().Value(statusKey{}).(map[string]cookieState)
+	return m[name].stale
+}
+
+// OnInvalid returns a middleware that calls f for requests whose cookie called
+// name is Invalid or Expired, eg to log tampering. If f returns false the
+// request is not passed on, and f must have replied to it. Use it after the
+// session middleware:
+//
+//	h = session.OnInvalid("session", func(w http.ResponseWriter, r *http.Request, s session.Status) bool {
+//		if s == session.Invalid {
+//			http.Error(w, "400 bad request", http.StatusBadRequest)
+//			return false
+//		}
+//		return true
+//	})(h)
+//	h = session.SessionMiddleware(secret, "session")(h)
+func OnInvalid(name string, f func(w http.ResponseWriter, r *http.Request, s Status) bool) func(http.Handler) http.HandlerFunc {
+	return func(next http.Handler) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if s := CookieStatus(r, name); s == Invalid || s == Expired {
+				if !f(w, r, s) {
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		}
+	}
+}
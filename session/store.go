@@ -0,0 +1,269 @@
+package session
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by Store.Load if there is no session with the id or
+// it has expired.
+var ErrNotFound = errors.New("session not found")
+
+// Store keeps the data of sessions on the server, keyed by the session id
+// sent in the cookie. Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns the data saved for id, or ErrNotFound.
+	Load(id string) ([]byte, error)
+
+	// Save saves data for id. If maxAge is positive the session expires
+	// after it.
+	Save(id string, data []byte, maxAge time.Duration) error
+
+	// Delete removes the session id. Deleting an unknown id is not an
+	// error.
+	Delete(id string) error
+}
+
+// sweepInterval is how often Save removes expired sessions, see Cleanup.
+const sweepInterval = time.Minute
+
+// now is time.Now, replaced by tests.
+var now = time.Now
+
+type memoryEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// MemoryStore is a Store that keeps sessions in memory. Sessions are lost when
+// the process exits and are not shared between processes. Expired sessions
+// are removed by Save once a minute.
+type MemoryStore struct {
+	mu        sync.Mutex
+	sessions  map[string]memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) Load(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !e.expires.IsZero() && now().After(e.expires) {
+		delete(s.sessions, id)
+		return nil, ErrNotFound
+	}
+	return e.data, nil
+}
+
+func (s *MemoryStore) Save(id string, data []byte, maxAge time.Duration) error {
+	e := memoryEntry{data: append([]byte(nil), data...)}
+	if maxAge > 0 {
+		e.expires = now().Add(maxAge)
+	}
+
+	s.mu.Lock()
+	s.sessions[id] = e
+	if now().Sub(s.lastSweep) > sweepInterval {
+		s.cleanup()
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Cleanup removes the expired sessions.
+func (s *MemoryStore) Cleanup() {
+	s.mu.Lock()
+	s.cleanup()
+	s.mu.Unlock()
+}
+
+func (s *MemoryStore) cleanup() {
+	t := now()
+	for id, e := range s.sessions {
+		if !e.expires.IsZero() && t.After(e.expires) {
+			delete(s.sessions, id)
+		}
+	}
+	s.lastSweep = t
+}
+
+func (s *MemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// FileStore is a Store that keeps each session in a file in Dir. The
+// directory must exist. Expired sessions are removed in the background by
+// Save once a minute.
+type FileStore struct {
+	Dir string
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	sweeping  bool
+}
+
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{Dir: dir}
+}
+
+// errInvalidID is returned for ids that are not safe to use as a file name.
+var errInvalidID = errors.New("invalid session id")
+
+func (s *FileStore) path(id string) (string, error) {
+	if !validID(id) {
+		return "", errInvalidID
+	}
+	return filepath.Join(s.Dir, "session_"+id), nil
+}
+
+// Load returns ErrNotFound for an expired session and removes its file.
+func (s *FileStore) Load(id string) ([]byte, error) {
+	p, err := s.path(id)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+
+	b, err := os.ReadFile(p)
+	if os.IsNotExist(err) || err == nil && len(b) < 8 {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the file starts with the expiry time in unix seconds, 0 for none.
+	if exp := int64(binary.BigEndian.Uint64(b)); exp != 0 && now().Unix() > exp {
+		os.Remove(p)
+		return nil, ErrNotFound
+	}
+	return b[8:], nil
+}
+
+// Save writes the session to a temporary file which is renamed, so that a
+// concurrent Load never sees a partial session.
+func (s *FileStore) Save(id string, data []byte, maxAge time.Duration) error {
+	p, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	var exp int64
+	if maxAge > 0 {
+		exp = now().Add(maxAge).Unix()
+	}
+	b := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(b, uint64(exp))
+	b = append(b, data...)
+
+	f, err := os.CreateTemp(s.Dir, ".session_")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b); err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), p)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	s.mu.Lock()
+	sweep := !s.sweeping && now().Sub(s.lastSweep) > sweepInterval
+	if sweep {
+		s.sweeping = true
+	}
+	s.mu.Unlock()
+	if sweep {
+		go s.Cleanup()
+	}
+	return nil
+}
+
+// Cleanup removes the files of expired sessions.
+func (s *FileStore) Cleanup() error {
+	defer func() {
+		s.mu.Lock()
+		s.sweeping = false
+		s.lastSweep = now()
+		s.mu.Unlock()
+	}()
+
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return err
+	}
+	t := now().Unix()
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), "session_") {
+			continue
+		}
+		p := filepath.Join(s.Dir, e.Name())
+		if exp, ok := readExpiry(p); ok && exp != 0 && t > exp {
+			os.Remove(p)
+		}
+	}
+	return nil
+}
+
+// readExpiry reads the expiry time at the start of the file p, see Load.
+func readExpiry(p string) (int64, bool) {
+	f, err := os.Open(p)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	var b [8]byte
+	if _, err := io.ReadFull(f, b[:]); err != nil {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(b[:])), true
+}
+
+func (s *FileStore) Delete(id string) error {
+	p, err := s.path(id)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// validID reports whether id only has characters of unpadded base64url, the
+// encoding of ids made by newID.
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
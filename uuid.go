@@ -0,0 +1,52 @@
+package web
+
+import (
+	"encoding/hex"
+	"fmt"
+)
+
+// UUID is a rfc4122 UUID. It can be used as the id of a ResourceHandleFuncOf.
+type UUID [16]byte
+
+// ParseUUID parses the canonical form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx, in
+// upper or lower case.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("web: invalid UUID %q", s)
+	}
+
+	b := []byte(s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:])
+	if _, err := hex.Decode(u[:], b); err != nil {
+		return UUID{}, fmt.Errorf("web: invalid UUID %q", s)
+	}
+	return u, nil
+}
+
+// String returns the canonical lower case form of u.
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(b []byte) error {
+	id, err := ParseUUID(string(b))
+	if err != nil {
+		return err
+	}
+	*u = id
+	return nil
+}
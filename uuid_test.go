@@ -0,0 +1,44 @@
+package web
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseUUID(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp string
+		err bool
+	}{
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", false},
+		{"6BA7B810-9DAD-11D1-80B4-00C04FD430C8", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", false},
+		{"00000000-0000-0000-0000-000000000000", "00000000-0000-0000-0000-000000000000", false},
+		{"6ba7b8109dad11d180b400c04fd430c8", "", true},
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430cg", "", true},
+		{"6ba7b810-9dad-11d1-80b4_00c04fd430c8", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		u, err := ParseUUID(test.s)
+		if test.err {
+			assert.NotNil(t, err, test.s)
+			continue
+		}
+		assert.Nil(t, err, test.s)
+		assert.Equal(t, test.exp, u.String(), test.s)
+	}
+}
+
+func TestUUID_JSON(t *testing.T) {
+	var v struct{ ID UUID }
+	err := json.Unmarshal([]byte(`{"ID":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}`), &v)
+	assert.Nil(t, err)
+
+	b, err := json.Marshal(v)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"ID":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}`, string(b))
+}
@@ -0,0 +1,181 @@
+package web
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is one version of an API. See Versions.
+type Version struct {
+	// Name is the version without the leading v, eg "2". Clients select it
+	// with a /v2 path segment, a version=2 media type parameter in Accept,
+	// or the Versions Header.
+	Name string
+
+	Handler http.Handler
+
+	// Deprecated is when the version was (or will be) deprecated. If set,
+	// responses get a Deprecation header (rfc9745).
+	Deprecated time.Time
+
+	// Sunset is when the version will stop working. If set, responses get a
+	// Sunset header (rfc8594).
+	Sunset time.Time
+}
+
+// SetHeaders sets the Deprecation and Sunset headers of v on h.
+func (v *Version) SetHeaders(h http.Header) {
+	if !v.Deprecated.IsZero() {
+		h.Set("Deprecation", "@"+strconv.FormatInt(v.Deprecated.Unix(), 10))
+	}
+	if !v.Sunset.IsZero() {
+		h.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+	}
+}
+
+// Versions is an http.Handler that dispatches to the handler of the version
+// the request asks for. The version is taken from, in order
+//
+//	the first path segment, eg /v2/users, which is stripped
+//	the version parameter of Accept, eg application/vnd.x+json; version=2
+//	the Header, if set
+//	Default
+//
+// An unknown version in the path gets 404 Not Found, an unknown version in
+// Accept or the Header gets 406 Not Acceptable.
+//
+// To version part of a router.Router, mount Versions so the version is the
+// first segment of the path:
+//
+//	r.Mount("/api", &web.Versions{Versions: []web.Version{{Name: "1", Handler: v1}}})
+type Versions struct {
+	Versions []Version
+
+	// Default is the name of the version used when the request doesn't ask
+	// for one. Defaults to the last of Versions.
+	Default string
+
+	// Header is a request header naming the version, eg Api-Version.
+	Header string
+
+	// Param is the media type parameter of Accept naming the version.
+	// Defaults to version.
+	Param string
+}
+
+func (vs *Versions) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v, inPath, status := vs.Select(r, r.URL.Path)
+	switch status {
+	case http.StatusNotFound:
+		http.NotFound(w, r)
+		return
+	case http.StatusNotAcceptable:
+		NotAcceptable(w, r)
+		return
+	}
+
+	v.SetHeaders(w.Header())
+
+	r = WithVersion(r, v.Name)
+	if inPath {
+		u := *r.URL
+		u.Path = strings.TrimPrefix(u.Path, "/v"+v.Name)
+		if u.Path == "" {
+			u.Path = "/"
+		}
+		u.RawPath = ""
+		r.URL = &u
+	}
+	v.Handler.ServeHTTP(w, r)
+}
+
+// Select returns the version r asks for. path is checked for a version
+// segment instead of r.URL.Path, so that a caller can pass the part of the
+// path it hasn't consumed yet, and inPath is set if its first segment named
+// the version. If there is no such version, v is nil and status is
+// http.StatusNotFound or http.StatusNotAcceptable.
+func (vs *Versions) Select(r *http.Request, path string) (v *Version, inPath bool, status int) {
+	seg := strings.TrimPrefix(path, "/")
+	if i := strings.IndexByte(seg, '/'); i >= 0 {
+		seg = seg[:i]
+	}
+	if isVersionSegment(seg) {
+		if v = vs.lookup(seg[1:]); v == nil {
+			return nil, false, http.StatusNotFound
+		}
+		return v, true, 0
+	}
+
+	name := vs.acceptVersion(r)
+	if name == "" && vs.Header != "" {
+		name = r.Header.Get(vs.Header)
+	}
+	if name != "" {
+		if v = vs.lookup(name); v == nil {
+			return nil, false, http.StatusNotAcceptable
+		}
+		return v, false, 0
+	}
+
+	name = vs.Default
+	if name == "" && len(vs.Versions) > 0 {
+		name = vs.Versions[len(vs.Versions)-1].Name
+	}
+	if v = vs.lookup(name); v == nil {
+		return nil, false, http.StatusNotFound
+	}
+	return v, false, 0
+}
+
+func (vs *Versions) lookup(name string) *Version {
+	name = strings.TrimPrefix(name, "v")
+	for i := range vs.Versions {
+		if vs.Versions[i].Name == name {
+			return &vs.Versions[i]
+		}
+	}
+	return nil
+}
+
+// acceptVersion returns the version parameter of the first media range in
+// Accept that has one.
+func (vs *Versions) acceptVersion(r *http.Request) string {
+	param := vs.Param
+	if param == "" {
+		param = "version"
+	}
+
+	for _, s := range strings.Split(strings.Join(r.Header["Accept"], ","), ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		if _, params, err := mime.ParseMediaType(s); err == nil && params[param] != "" {
+			return params[param]
+		}
+	}
+	return ""
+}
+
+// isVersionSegment reports whether seg looks like v1 or v2.1.
+func isVersionSegment(seg string) bool {
+	return len(seg) > 1 && seg[0] == 'v' && seg[1] >= '0' && seg[1] <= '9'
+}
+
+type versionKey struct{}
+
+// WithVersion returns a shallow copy of r that carries the version name. See
+// RequestVersion.
+func WithVersion(r *http.Request, name string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), versionKey{}, name))
+}
+
+// RequestVersion returns the name of the version selected by Versions for r,
+// or "" if there is none.
+func RequestVersion(r *http.Request) string {
+	name, _ := r.Context().Value(versionKey{}).(string)
+	return name
+}
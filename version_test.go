@@ -0,0 +1,91 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersions(t *testing.T) {
+	version := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s %s", name, RequestVersion(r), r.URL.Path)
+		}
+	}
+
+	deprecated := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	vs := &Versions{
+		Versions: []Version{
+			{Name: "1", Handler: version("one"), Deprecated: deprecated, Sunset: sunset},
+			{Name: "2", Handler: version("two")},
+			{Name: "3", Handler: version("three")},
+		},
+		Default: "2",
+		Header:  "Api-Version",
+	}
+
+	tests := []struct {
+		path, header, value string
+		code                int
+		exp                 string
+	}{
+		{"/v1/users", "", "", 200, "one 1 /users"},
+		{"/v3", "", "", 200, "three 3 /"},
+		{"/users", "", "", 200, "two 2 /users"},
+		{"/users", "Accept", "application/vnd.x+json; version=3", 200, "three 3 /users"},
+		{"/users", "Accept", "text/html, application/vnd.x+json;version=1", 200, "one 1 /users"},
+		{"/users", "Api-Version", "1", 200, "one 1 /users"},
+		{"/users", "Api-Version", "v3", 200, "three 3 /users"},
+		// the path wins
+		{"/v2/users", "Api-Version", "1", 200, "two 2 /users"},
+		{"/v4/users", "", "", 404, "404 page not found\n"},
+		{"/users", "Api-Version", "4", 406, "406 not acceptable\n"},
+		// not a version segment
+		{"/vip", "", "", 200, "two 2 /vip"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.path, nil)
+		if test.header != "" {
+			r.Header.Set(test.header, test.value)
+		}
+		w := httptest.NewRecorder()
+		vs.ServeHTTP(w, r)
+
+		assert.Equal(t, test.code, w.Code, test.path)
+		assert.Equal(t, test.exp, w.Body.String(), test.path)
+	}
+
+	w := httptest.NewRecorder()
+	vs.ServeHTTP(w, httptest.NewRequest("GET", "/v1", nil))
+	assert.Equal(t, "@1704067200", w.Header().Get("Deprecation"))
+	assert.Equal(t, "Wed, 01 Jan 2025 00:00:00 GMT", w.Header().Get("Sunset"))
+
+	w = httptest.NewRecorder()
+	vs.ServeHTTP(w, httptest.NewRequest("GET", "/v2", nil))
+	assert.Equal(t, "", w.Header().Get("Deprecation"))
+}
+
+func TestVersions_DefaultLast(t *testing.T) {
+	vs := &Versions{
+		Versions: []Version{{Name: "1"}, {Name: "2"}},
+	}
+
+	v, inPath, status := vs.Select(httptest.NewRequest("GET", "/", nil), "users")
+	assert.Equal(t, "2", v.Name)
+	assert.False(t, inPath)
+	assert.Equal(t, 0, status)
+
+	v, inPath, _ = vs.Select(httptest.NewRequest("GET", "/", nil), "v1/users")
+	assert.Equal(t, "1", v.Name)
+	assert.True(t, inPath)
+
+	_, _, status = (&Versions{}).Select(httptest.NewRequest("GET", "/", nil), "/")
+	assert.Equal(t, 404, status)
+}